@@ -0,0 +1,90 @@
+// Arbitrary-precision arithmetic with math/big
+
+package main
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Conversions between math/big and native types. Each reports whether the
+// native value is exactly the big one, instead of silently rounding.
+
+// int64Of returns x as an int64; ok is false if x does not fit.
+func int64Of(x *big.Int) (n int64, ok bool) {
+	if !x.IsInt64() {
+		return 0, false
+	}
+	return x.Int64(), true
+}
+
+// float64Of returns the float64 nearest to x and whether it equals x.
+func float64Of(x *big.Rat) (f float64, exact bool) {
+	return x.Float64()
+}
+
+// float32Of returns the float32 nearest to x and whether it equals x.
+func float32Of(x *big.Rat) (f float32, exact bool) {
+	return x.Float32()
+}
+
+// ratOf returns the exact value of f as a fraction. Every finite float is
+// a fraction with a power-of-two denominator, so this never rounds.
+func ratOf(f float64) *big.Rat {
+	return new(big.Rat).SetFloat64(f)
+}
+
+// floatOf converts x to a big.Float with prec bits of mantissa and reports
+// how it was rounded: big.Exact, big.Below or big.Above.
+func floatOf(x *big.Int, prec uint) (*big.Float, big.Accuracy) {
+	f := new(big.Float).SetPrec(prec).SetInt(x)
+	return f, f.Acc()
+}
+
+// lesson: bignum section=explicit-type-conversion--casting goVersion=go1.9
+func bigArith() {
+
+	// 0.1 and 0.2 have no exact binary representation, so their float64 sum
+	// is not the float64 nearest to 0.3
+	a, b := 0.1, 0.2
+	fmt.Println("float64: 0.1 + 0.2 =", a+b, "== 0.3:", a+b == 0.3)
+	fmt.Println("float64 0.1 is exactly", ratOf(0.1).FloatString(55))
+
+	// big.Rat keeps exact fractions: 1/10 + 2/10 is exactly 3/10
+	x := new(big.Rat).Add(big.NewRat(1, 10), big.NewRat(2, 10))
+	fmt.Println("big.Rat: 1/10 + 2/10 =", x, "== 3/10:", x.Cmp(big.NewRat(3, 10)) == 0)
+	f, exact := float64Of(x)
+	fmt.Println("back to float64:", f, "exact:", exact)
+
+	// Factorials outgrow int64 at 21!; the int64 product silently wraps
+	fact := big.NewInt(1)
+	var wrapped int64 = 1
+	for i := int64(1); i <= 25; i++ {
+		fact.Mul(fact, big.NewInt(i))
+		wrapped *= i
+		if i == 20 || i == 21 || i == 25 {
+			if n, ok := int64Of(fact); ok {
+				fmt.Printf("%d! = %v fits in int64: %d\n", i, fact, n)
+			} else {
+				fmt.Printf("%d! = %v does not fit in int64, which wrapped to %d\n", i, fact, wrapped)
+			}
+		}
+	}
+
+	// float64 has a 53-bit mantissa: 25! needs more, so it is rounded
+	f25, acc := floatOf(fact, 53)
+	fmt.Println("25! as float64:", f25.Text('f', 0), "rounded:", acc)
+	f25, acc = floatOf(fact, 128)
+	fmt.Println("25! with 128 bits:", f25.Text('f', 0), "rounded:", acc)
+
+	// float32(3.14) is not 3.14: it is the nearest fraction with a 24-bit
+	// mantissa. Widening it to float64 is exact, and so shows the error
+	var pi32 float32 = 3.14
+	r := ratOf(float64(pi32))
+	fmt.Println("float32 3.14 =", r, "=", r.FloatString(22))
+	fmt.Println("float64(float32 3.14) =", float64(pi32))
+	p32, exact := float32Of(r)
+	fmt.Println("back to float32:", p32, "exact:", exact)
+	p32, exact = float32Of(big.NewRat(314, 100))
+	fmt.Println("314/100 to float32:", p32, "exact:", exact)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"basics/internal/lesson"
+)
+
+var cmdBench = &command{
+	name:     "bench",
+	args:     "[-dir dir] lesson...",
+	short:    "run lessons with their benchmarks enabled",
+	graceful: true,
+}
+
+func init() {
+	cmdBench.run = runBench
+}
+
+// runBench runs lessons with BASICS_BENCH set. Benchmark timings change
+// from run to run, so the output is printed but never compared with the
+// golden files.
+func runBench(ctx context.Context, args []string) error {
+	fs := cmdBench.flags()
+	dir := fs.String("dir", ".", "module `directory` containing the lessons")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	lessons, err := selectLessons(*dir, fs.Args())
+	if err != nil {
+		return err
+	}
+	b, err := lesson.NewBuild(ctx, *dir)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	for i, l := range lessons {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("== %s\n", l.Name)
+		if err := lesson.CheckOS(l); err != nil {
+			fmt.Printf("SKIP %v\n", err)
+			continue
+		}
+		out, err := b.Run(ctx, l.Name, "BASICS_BENCH=1")
+		os.Stdout.Write(out)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", l.Name, err)
+		}
+	}
+	return nil
+}
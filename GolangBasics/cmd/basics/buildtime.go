@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"basics/internal/buildtime"
+	"basics/internal/modload"
+)
+
+var cmdBuildtime = &command{
+	name:     "buildtime",
+	args:     "[-dir dir] [-std] [packages]",
+	short:    "time compile, asm and link per package, with a cold and a warm build cache",
+	graceful: true,
+}
+
+func init() {
+	cmdBuildtime.run = runBuildtime
+}
+
+func runBuildtime(ctx context.Context, args []string) error {
+	fs := cmdBuildtime.flags()
+	dir := fs.String("dir", ".", "module `directory` to build")
+	std := fs.Bool("std", false, "list standard library packages one by one instead of as a single row")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	pkgs, err := modload.List(ctx, *dir, append([]string{"-deps"}, patterns...)...)
+	if err != nil {
+		return err
+	}
+	standard := make(map[string]bool)
+	for _, p := range pkgs {
+		standard[p.ImportPath] = p.Standard
+	}
+	res, err := buildtime.Measure(ctx, *dir, exe, patterns...)
+	if err != nil {
+		return err
+	}
+
+	commit := "unknown commit"
+	cmd := exec.CommandContext(ctx, "git", "describe", "--always", "--dirty")
+	cmd.Dir = *dir
+	if out, err := cmd.Output(); err == nil {
+		commit = strings.TrimSpace(string(out))
+	}
+	fmt.Printf("Build times of %s at %s (%s %s/%s, %d CPUs)\n\n", strings.Join(patterns, " "), commit, runtime.Version(), runtime.GOOS, runtime.GOARCH, runtime.NumCPU())
+
+	// Sum the time of each tool per package row; asm runs several times
+	// for a package with assembly files.
+	type row struct {
+		name       string
+		cold, warm map[string]time.Duration
+	}
+	rows := make(map[string]*row)
+	nstd := make(map[string]bool)
+	add := func(recs []buildtime.Record, warm bool) {
+		for _, r := range recs {
+			name := r.Package
+			if standard[name] && !*std {
+				nstd[name] = true
+				name = "std"
+			}
+			rw := rows[name]
+			if rw == nil {
+				rw = &row{name: name, cold: make(map[string]time.Duration), warm: make(map[string]time.Duration)}
+				rows[name] = rw
+			}
+			if warm {
+				rw.warm[r.Tool] += r.Duration
+			} else {
+				rw.cold[r.Tool] += r.Duration
+			}
+		}
+	}
+	add(res.Cold.Records, false)
+	add(res.Warm.Records, true)
+	if r := rows["std"]; r != nil {
+		r.name = fmt.Sprintf("std (%d packages)", len(nstd))
+	}
+	var list []*row
+	for _, r := range rows {
+		list = append(list, r)
+	}
+	// Standard library first, then the module's packages by path.
+	slices.SortFunc(list, func(a, b *row) int {
+		if sa, sb := standard[a.name] || strings.HasPrefix(a.name, "std ("), standard[b.name] || strings.HasPrefix(b.name, "std ("); sa != sb {
+			if sa {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.name, b.name)
+	})
+
+	tools := []string{"compile", "asm", "link"}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "Package\tcold compile\tcold asm\tcold link\twarm compile\twarm asm\twarm link")
+	total := func(m map[string]time.Duration) string {
+		var cells []string
+		for _, t := range tools {
+			cells = append(cells, msec(m[t]))
+		}
+		return strings.Join(cells, "\t")
+	}
+	for _, r := range list {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.name, total(r.cold), total(r.warm))
+	}
+	fmt.Fprintf(tw, "wall clock\t%s\t\t\t%s\n", msec(res.Cold.Wall), msec(res.Warm.Wall))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Println("\n\"-\": the tool did not run; its output came from the build cache.")
+	return nil
+}
+
+// msec formats d in milliseconds, or "-" for zero.
+func msec(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0fms", float64(d)/float64(time.Millisecond))
+}
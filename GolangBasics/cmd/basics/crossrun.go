@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"syscall"
+
+	"basics/internal/layout"
+	"basics/internal/lesson"
+)
+
+var cmdCrossrun = &command{
+	name:     "crossrun",
+	args:     "[-dir dir] [lesson...]",
+	short:    "run lessons as 386 binaries and diff their output and struct sizes against amd64",
+	graceful: true,
+}
+
+func init() {
+	cmdCrossrun.run = runCrossrun
+}
+
+// runCrossrun compares the lessons on a 4-byte word (386) with an 8-byte
+// word (amd64). The struct layouts are compared statically on any
+// machine; running needs an amd64 Linux kernel that can execute 32-bit
+// binaries, and is skipped with an explanation otherwise.
+func runCrossrun(ctx context.Context, args []string) error {
+	fs := cmdCrossrun.flags()
+	dir := fs.String("dir", ".", "module `directory` containing the lessons")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	lessons, err := selectLessons(*dir, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("unsafe.Sizeof, amd64 → 386:")
+	wide, err := layout.Analyze(*dir, "amd64")
+	if err != nil {
+		return err
+	}
+	narrow, err := layout.Analyze(*dir, "386")
+	if err != nil {
+		return err
+	}
+	same := 0
+	for i, s := range wide {
+		changes := layout.Compare(s, narrow[i])
+		if len(changes) == 0 {
+			same++
+			continue
+		}
+		fmt.Printf("  %s (%s):\n", s.Name, s.Pos)
+		for _, c := range changes {
+			fmt.Printf("    %s\n", c)
+		}
+	}
+	fmt.Printf("  %d of %d structs have the same layout\n\n", same, len(wide))
+
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		fmt.Printf("SKIP running: needs linux/amd64 to run both binaries, this is %s/%s\n", runtime.GOOS, runtime.GOARCH)
+		return nil
+	}
+	modGo, err := lesson.ModuleGoVersion(*dir)
+	if err != nil {
+		return err
+	}
+	native, err := lesson.NewBuild(ctx, *dir)
+	if err != nil {
+		return err
+	}
+	defer native.Close()
+	b386, err := lesson.NewBuild(ctx, *dir, "GOARCH=386")
+	if err != nil {
+		return err
+	}
+	defer b386.Close()
+
+	for _, l := range lessons {
+		var verr *lesson.VersionError
+		if err := lesson.CheckVersion(l, modGo); errors.As(err, &verr) {
+			fmt.Printf("SKIP %s: %v\n", l.Name, err)
+			continue
+		}
+		want, err := native.Run(ctx, l.Name)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			fmt.Printf("FAIL %s: amd64: %v\n", l.Name, err)
+			continue
+		}
+		got, err := b386.Run(ctx, l.Name)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, syscall.ENOEXEC) {
+			fmt.Println("SKIP running: this kernel cannot execute 32-bit binaries (exec format error).")
+			fmt.Println("     Enable IA-32 emulation (CONFIG_IA32_EMULATION, or boot with ia32_emulation=1) to compare output.")
+			return nil
+		}
+		if err != nil {
+			fmt.Printf("FAIL %s: 386: %v\n", l.Name, err)
+			continue
+		}
+		d := lesson.Diff(lesson.Normalize(want), lesson.Normalize(got))
+		if d == "" {
+			fmt.Printf("same %s\n", l.Name)
+			continue
+		}
+		fmt.Printf("diff %s (- amd64, + 386):\n%s", l.Name, changedLines(d))
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"basics/internal/deps"
+)
+
+var cmdDeps = &command{
+	name:     "deps",
+	args:     "[-dir dir] [-dot] [-std] [packages]",
+	short:    "show the module's import graph as a tree or as Graphviz DOT",
+	graceful: true,
+}
+
+func init() {
+	cmdDeps.run = runDeps
+}
+
+func runDeps(ctx context.Context, args []string) error {
+	fs := cmdDeps.flags()
+	dir := fs.String("dir", ".", "module `directory`")
+	dot := fs.Bool("dot", false, "print the graph in DOT format (go run ./cmd/basics deps -dot | dot -Tsvg)")
+	std := fs.Bool("std", false, "with -dot, include the standard library's own imports")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	g, err := deps.Load(ctx, *dir, patterns...)
+	if err != nil {
+		return err
+	}
+	if *dot {
+		return g.WriteDOT(os.Stdout, *std)
+	}
+	if err := g.WriteTree(os.Stdout); err != nil {
+		return err
+	}
+
+	local := g.Local()
+	files, lines := 0, 0
+	for _, n := range local {
+		files += n.Files
+		lines += n.Lines
+	}
+	fmt.Printf("\n%d module packages (%d files, %d lines), %d standard library packages\n", len(local), files, lines, len(g.Standard()))
+
+	risks := g.CycleRisks()
+	if len(risks) == 0 {
+		return nil
+	}
+	fmt.Println("\nCycles in waiting (importing any of these would close an import cycle):")
+	for _, r := range risks {
+		fmt.Printf("  %s must not import %s\n", r.Path, strings.Join(r.Forbidden, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/version"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"basics/internal/lesson"
+)
+
+var cmdDoctor = &command{
+	name:     "doctor",
+	args:     "[-dir dir]",
+	short:    "check that the Go installation and environment can run the tools",
+	graceful: true,
+}
+
+func init() {
+	cmdDoctor.run = runDoctor
+}
+
+// finding is the result of one doctor check. A failed check comes with a
+// fix the user can apply.
+type finding struct {
+	status string // "ok", "WARN" or "FAIL"
+	what   string
+	detail string
+	fix    string
+}
+
+func runDoctor(ctx context.Context, args []string) error {
+	fs := cmdDoctor.flags()
+	dir := fs.String("dir", ".", "module `directory`")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	env, err := goEnv(ctx, *dir, "GOVERSION", "GOROOT", "GOCACHE", "GOOS", "GOARCH")
+	if err != nil {
+		fmt.Println("FAIL  go command:", err)
+		fmt.Println("      fix: install Go from https://go.dev/dl/ and put its bin directory on PATH")
+		return errors.New("no usable go command")
+	}
+	findings := []finding{
+		checkGoVersion(*dir, env["GOVERSION"]),
+		checkWritable("GOCACHE", env["GOCACHE"], "export GOCACHE=$HOME/.cache/go-build (or any writable directory)"),
+		checkWritable("temp directory", os.TempDir(), "export TMPDIR to a writable directory with free space"),
+		checkSpec(env["GOROOT"]),
+		checkRace(ctx, env),
+		checkTerminal(),
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	failed := 0
+	for _, f := range findings {
+		fmt.Printf("%-4s  %s: %s\n", f.status, f.what, f.detail)
+		if f.fix != "" {
+			fmt.Printf("      fix: %s\n", f.fix)
+		}
+		if f.status == "FAIL" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(findings))
+	}
+	return nil
+}
+
+// goEnv returns the values of the named go env variables, as the go
+// command sees them in dir (after any toolchain switch).
+func goEnv(ctx context.Context, dir string, names ...string) (map[string]string, error) {
+	cmd := exec.CommandContext(ctx, "go", append([]string{"env"}, names...)...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			return nil, fmt.Errorf("go env: %v\n%s", err, exit.Stderr)
+		}
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(lines) != len(names) {
+		return nil, fmt.Errorf("go env: unexpected output %q", out)
+	}
+	env := make(map[string]string)
+	for i, name := range names {
+		env[name] = lines[i]
+	}
+	return env, nil
+}
+
+func checkGoVersion(dir, goVersion string) finding {
+	f := finding{what: "go version"}
+	modGo, err := lesson.ModuleGoVersion(dir)
+	if err != nil {
+		f.status, f.detail = "FAIL", err.Error()
+		f.fix = "run the tools from the module root, or pass -dir"
+		return f
+	}
+	// Development builds report e.g. "devel go1.24-abcdef Tue ...".
+	have := goVersion
+	for _, field := range strings.Fields(goVersion) {
+		if v, _, _ := strings.Cut(field, "-"); version.IsValid(v) {
+			have = v
+			break
+		}
+	}
+	switch {
+	case !version.IsValid(have):
+		f.status, f.detail = "WARN", fmt.Sprintf("cannot tell whether %q is at least %s (go.mod)", goVersion, modGo)
+	case version.Compare(have, modGo) < 0:
+		f.status, f.detail = "FAIL", fmt.Sprintf("%s is older than the go %s directive in go.mod", have, strings.TrimPrefix(modGo, "go"))
+		f.fix = fmt.Sprintf("install %s or later from https://go.dev/dl/, or set GOTOOLCHAIN=auto to let the go command download it", modGo)
+	default:
+		f.status, f.detail = "ok", fmt.Sprintf("%s, go.mod needs %s", have, modGo)
+	}
+	return f
+}
+
+// checkWritable checks that a file can be created in dir.
+func checkWritable(what, dir, fix string) finding {
+	f := finding{what: what}
+	if dir == "" || dir == "off" {
+		f.status, f.detail, f.fix = "FAIL", "disabled", fix
+		return f
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		f.status, f.detail, f.fix = "FAIL", err.Error(), fix
+		return f
+	}
+	tmp, err := os.CreateTemp(dir, "basics-doctor-")
+	if err != nil {
+		f.status, f.detail, f.fix = "FAIL", fmt.Sprintf("%s is not writable: %v", dir, err), fix
+		return f
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+	f.status, f.detail = "ok", dir+" is writable"
+	return f
+}
+
+// checkSpec looks for the language specification that ships with Go.
+// Some Linux distributions package the doc directory separately.
+func checkSpec(goroot string) finding {
+	f := finding{what: "language spec"}
+	path := filepath.Join(goroot, "doc", "go_spec.html")
+	if _, err := os.Stat(path); err != nil {
+		f.status, f.detail = "FAIL", fmt.Sprintf("%s is missing", path)
+		f.fix = "install the Go docs package of your distribution (e.g. golang-doc), or a release from https://go.dev/dl/; the spec is also at https://go.dev/ref/spec"
+		return f
+	}
+	f.status, f.detail = "ok", path
+	return f
+}
+
+// checkRace builds an empty program with -race, so the toolchain itself
+// says whether go test -race and go run -race can work: the platform must
+// be supported and, except on macOS, cgo must be on with a C compiler
+// available.
+func checkRace(ctx context.Context, env map[string]string) finding {
+	f := finding{what: "race detector"}
+	platform := env["GOOS"] + "/" + env["GOARCH"]
+	tmp, err := os.MkdirTemp("", "basics-doctor-")
+	if err != nil {
+		f.status, f.detail = "WARN", err.Error()
+		f.fix = "export TMPDIR to a writable directory with free space"
+		return f
+	}
+	defer os.RemoveAll(tmp)
+	for name, src := range map[string]string{
+		"go.mod":  "module racecheck\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(tmp, name), []byte(src), 0o644); err != nil {
+			f.status, f.detail = "WARN", err.Error()
+			return f
+		}
+	}
+	cmd := exec.CommandContext(ctx, "go", "build", "-race", "-o", os.DevNull, ".")
+	cmd.Dir = tmp
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		f.status, f.detail = "ok", "go build -race works on "+platform
+		return f
+	}
+	// The last line says why, e.g. "-race is not supported on linux/386"
+	// or `cgo: C compiler "gcc" not found: ...`.
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	msg := strings.TrimPrefix(lines[len(lines)-1], "go: ")
+	if msg == "" {
+		msg = err.Error()
+	}
+	f.status, f.detail = "WARN", "go build -race fails: "+msg
+	switch {
+	case strings.Contains(msg, "not supported on"):
+		f.fix = "-race needs a 64-bit platform such as linux/amd64, darwin/arm64 or windows/amd64; see go help build"
+	case strings.Contains(msg, "requires cgo"):
+		f.fix = "export CGO_ENABLED=1 and install a C compiler"
+	case strings.Contains(msg, "C compiler"):
+		f.fix = "install gcc or clang (e.g. apt install gcc), or point CC at one"
+	default:
+		f.fix = "run go build -race in a module for the full error"
+	}
+	return f
+}
+
+// checkTerminal checks what the trace diagrams need from the terminal:
+// they are drawn with box-drawing characters such as ├─ and →.
+func checkTerminal() finding {
+	f := finding{what: "terminal"}
+	st, err := os.Stdout.Stat()
+	tty := err == nil && st.Mode()&os.ModeCharDevice != 0
+	term := os.Getenv("TERM")
+	locale := firstNonEmpty(os.Getenv("LC_ALL"), os.Getenv("LC_CTYPE"), os.Getenv("LANG"))
+	utf8 := strings.Contains(strings.ToUpper(strings.ReplaceAll(locale, "-", "")), "UTF8")
+	var notes []string
+	if !tty {
+		notes = append(notes, "output is not a terminal")
+	} else if term == "" || term == "dumb" {
+		notes = append(notes, fmt.Sprintf("TERM=%q", term))
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		notes = append(notes, "NO_COLOR is set")
+	}
+	switch {
+	case !utf8 && locale != "":
+		f.status, f.detail = "WARN", fmt.Sprintf("locale %s is not UTF-8, so diagrams (├─ →) may not display", locale)
+		f.fix = "export LANG=C.UTF-8"
+	case !utf8:
+		f.status, f.detail = "WARN", "no locale set (LANG, LC_ALL), so diagrams (├─ →) may not display"
+		f.fix = "export LANG=C.UTF-8"
+	default:
+		f.status, f.detail = "ok", "UTF-8 locale "+locale
+	}
+	if len(notes) > 0 {
+		f.detail += "; " + strings.Join(notes, ", ")
+	}
+	return f
+}
+
+func firstNonEmpty(s ...string) string {
+	for _, v := range s {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"basics/internal/escape"
+)
+
+var cmdErrorcheck = &command{
+	name:     "errorcheck",
+	args:     "[-dir dir] [-v]",
+	short:    "check ESCAPE and INLINE comments in lessons and README examples against -gcflags=-m",
+	graceful: true,
+}
+
+func init() {
+	cmdErrorcheck.run = runErrorcheck
+}
+
+func runErrorcheck(ctx context.Context, args []string) error {
+	fs := cmdErrorcheck.flags()
+	dir := fs.String("dir", ".", "module `directory` containing the lessons")
+	verbose := fs.Bool("v", false, "print the diagnostics on each annotated line")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	m, err := escape.LoadModule(ctx, *dir)
+	if err != nil {
+		return err
+	}
+	exps, diags, files := m.Expectations, m.Diagnostics, m.Files
+
+	problems := escape.Check(exps, diags)
+	slices.SortStableFunc(diags, func(a, b escape.Diagnostic) int {
+		return cmp.Or(a.Line-b.Line, a.Col-b.Col)
+	})
+	failed := 0
+	for _, f := range files {
+		n := 0
+		var lines []int
+		for _, e := range exps {
+			if e.File == f {
+				n++
+				lines = append(lines, e.Line)
+			}
+		}
+		var ps []string
+		for _, p := range problems {
+			if p.File == f {
+				ps = append(ps, "    "+p.String())
+			}
+		}
+		if len(ps) == 0 {
+			fmt.Printf("ok   %s (%d expectations)\n", f, n)
+		} else {
+			failed++
+			fmt.Printf("FAIL %s\n%s\n", f, strings.Join(ps, "\n"))
+		}
+		if *verbose {
+			for _, d := range diags {
+				if _, ok := escape.KindOf(d.Msg); ok && d.File == f && slices.Contains(lines, d.Line) {
+					fmt.Println("    " + d.String())
+				}
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d problems in %d of %d files", len(problems), failed, len(files))
+	}
+	return nil
+}
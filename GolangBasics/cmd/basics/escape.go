@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"basics/internal/escape"
+	"basics/internal/lesson"
+)
+
+var cmdEscape = &command{
+	name:     "escape",
+	args:     "[-dir dir] [-gcflags flags] [-match regexp] lesson",
+	short:    "print the compiler's inlining, devirtualization and escape decisions for a lesson",
+	graceful: true,
+}
+
+func init() {
+	cmdEscape.run = runEscape
+}
+
+func runEscape(ctx context.Context, args []string) error {
+	fs := cmdEscape.flags()
+	dir := fs.String("dir", ".", "module `directory` containing the lessons")
+	gcflags := fs.String("gcflags", "-m", "compiler `flags`; -m=2 explains each decision")
+	match := fs.String("match", "", "only print diagnostics matching `regexp`")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	re, err := regexp.Compile(*match)
+	if err != nil {
+		return err
+	}
+	lessons, err := lesson.Load(*dir)
+	if err != nil {
+		return err
+	}
+	l, err := lesson.Find(lessons, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	diags, err := escape.Build(ctx, *dir, *gcflags)
+	if err != nil {
+		return err
+	}
+	// The compiler reports in phase order (inlining, then escape
+	// analysis); sort by position so each line's decisions read together.
+	slices.SortStableFunc(diags, func(a, b escape.Diagnostic) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Col - b.Col
+	})
+	file, err := filepath.Rel(*dir, l.File)
+	if err != nil {
+		return err
+	}
+	file = filepath.ToSlash(file)
+	for _, d := range diags {
+		if d.File == file && re.MatchString(d.Msg) {
+			fmt.Println(d)
+		}
+	}
+	return nil
+}
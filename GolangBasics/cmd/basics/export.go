@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"basics/internal/lesson"
+	"basics/internal/txtar"
+)
+
+var cmdExport = &command{
+	name:     "export",
+	args:     "[-dir dir] lesson | -check [lesson...]",
+	short:    "write a lesson as a self-contained txtar archive for the Go Playground",
+	graceful: true,
+}
+
+func init() {
+	cmdExport.run = runExport
+}
+
+func runExport(ctx context.Context, args []string) error {
+	fs := cmdExport.flags()
+	dir := fs.String("dir", ".", "module `directory` containing the lessons")
+	check := fs.Bool("check", false, "check that lessons survive export, go run and import unchanged")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *check {
+		lessons, err := selectLessons(*dir, fs.Args())
+		if err != nil {
+			return err
+		}
+		failed := 0
+		for _, l := range lessons {
+			if err := checkExport(ctx, *dir, l); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				fmt.Printf("FAIL %s: %v\n", l.Name, err)
+				failed++
+				continue
+			}
+			fmt.Printf("ok   %s\n", l.Name)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d lessons failed", failed, len(lessons))
+		}
+		return nil
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	lessons, err := lesson.Load(*dir)
+	if err != nil {
+		return err
+	}
+	l, err := lesson.Find(lessons, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	ar, err := lesson.Export(*dir, l)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(txtar.Format(ar))
+	return err
+}
+
+// checkExport checks the round trips of an exported lesson: the archive
+// reads back as itself, runs on its own with the expected output, and
+// imports into a copy of the module as an identical lesson.
+func checkExport(ctx context.Context, dir string, l lesson.Lesson) error {
+	ar, err := lesson.Export(dir, l)
+	if err != nil {
+		return err
+	}
+	data := txtar.Format(ar)
+	if !bytes.Equal(txtar.Format(txtar.Parse(data)), data) {
+		return fmt.Errorf("archive changes when parsed and formatted again")
+	}
+
+	tmp, err := os.MkdirTemp("", "basics-export-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	play := filepath.Join(tmp, "play")
+	if err := extract(ar, play); err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, "go", "run", ".")
+	cmd.Dir = play
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("go run of the archive: %v\n%s", err, out)
+	}
+	if d := lesson.Diff(ar.Lookup("testdata/"+l.Name+".golden").Data, lesson.Normalize(out)); d != "" {
+		return fmt.Errorf("archive output differs from golden file:\n%s", d)
+	}
+
+	mod := filepath.Join(tmp, "module")
+	if err := lesson.CopyPackage(dir, mod); err != nil {
+		return err
+	}
+	copyName := l.Name + "_copy"
+	cl, err := lesson.Import(mod, ar, copyName)
+	if err != nil {
+		return fmt.Errorf("import: %v", err)
+	}
+	again, err := lesson.Export(mod, cl)
+	if err != nil {
+		return fmt.Errorf("export after import: %v", err)
+	}
+	for _, f := range ar.Files {
+		name := strings.Replace(f.Name, "testdata/"+l.Name+".", "testdata/"+copyName+".", 1)
+		g := again.Lookup(name)
+		switch {
+		case g == nil:
+			return fmt.Errorf("export after import has no %s", name)
+		case f.Name != "go.mod" && !bytes.Equal(f.Data, g.Data):
+			return fmt.Errorf("%s changed after import and export:\n%s", f.Name, lesson.Diff(f.Data, g.Data))
+		}
+	}
+	return nil
+}
+
+// extract writes the files of ar under dir.
+func extract(ar *txtar.Archive, dir string) error {
+	for _, f := range ar.Files {
+		path := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if !strings.HasPrefix(path, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("archive file %s is outside the directory", f.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, f.Data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"basics/internal/lesson"
+	"basics/internal/txtar"
+)
+
+var cmdImport = &command{
+	name:  "import",
+	args:  "[-dir dir] [-as name] file.txtar",
+	short: "add a lesson from a txtar archive written by export",
+}
+
+func init() {
+	cmdImport.run = runImport
+}
+
+func runImport(ctx context.Context, args []string) error {
+	fs := cmdImport.flags()
+	dir := fs.String("dir", ".", "module `directory` containing the lessons")
+	as := fs.String("as", "", "import the lesson under this `name`")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	var data []byte
+	var err error
+	if fs.Arg(0) == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+	l, err := lesson.Import(*dir, txtar.Parse(data), *as)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("added lesson %s (%s, run %s)\n", l.Name, l.File, l.Func)
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"basics/internal/layout"
+	"basics/internal/lesson"
+)
+
+var cmdLayout = &command{
+	name:  "layout",
+	args:  "[-dir dir] [-arch goarch] [-check | -update] [type...]",
+	short: "explain the memory layout of the module's struct types",
+}
+
+func init() {
+	cmdLayout.run = runLayout
+}
+
+func runLayout(ctx context.Context, args []string) error {
+	fs := cmdLayout.flags()
+	dir := fs.String("dir", ".", "module `directory` to analyze")
+	arch := fs.String("arch", runtime.GOARCH, "`GOARCH` whose sizes and alignments to use")
+	check := fs.Bool("check", false, "compare the report with testdata/layout_GOARCH.golden")
+	update := fs.Bool("update", false, "rewrite testdata/layout_GOARCH.golden")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	structs, err := layout.Analyze(*dir, *arch)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, s := range structs {
+		if !matchType(s.Name, fs.Args()) {
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		s.Write(&buf)
+	}
+
+	golden := filepath.Join(*dir, "testdata", "layout_"+*arch+".golden")
+	switch {
+	case *update:
+		return os.WriteFile(golden, buf.Bytes(), 0o644)
+	case *check:
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			return err
+		}
+		if diff := lesson.Diff(want, buf.Bytes()); diff != "" {
+			return fmt.Errorf("layout differs from %s:\n%s", golden, diff)
+		}
+		fmt.Printf("ok   layout %s\n", *arch)
+		return nil
+	}
+	_, err = os.Stdout.Write(buf.Bytes())
+	return err
+}
+
+// matchType reports whether the struct called name was asked for. Local
+// types match by their full name ("main.example") or by the bare type
+// name ("example").
+func matchType(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if p == name || strings.HasSuffix(name, "."+p) {
+			return true
+		}
+	}
+	return false
+}
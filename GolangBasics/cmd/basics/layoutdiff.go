@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"basics/internal/layout"
+)
+
+var cmdLayoutDiff = &command{
+	name:     "layout-diff",
+	args:     "[-dir dir] [-arch goarch,...] rev1 rev2",
+	short:    "report struct layout changes between two git revisions",
+	graceful: true,
+}
+
+func init() {
+	cmdLayoutDiff.run = runLayoutDiff
+}
+
+// runLayoutDiff checks out each revision into a temporary git worktree,
+// type-checks the module there, and reports every struct whose size,
+// alignment or field offsets differ on any of the GOARCHes. The revision
+// "." is the working tree, uncommitted changes included.
+//
+// Structs passed to encoding/binary, measured or converted with unsafe,
+// or used as shared memory are marked with "!"; if any of them changed
+// the command fails, so the report can gate a review.
+func runLayoutDiff(ctx context.Context, args []string) error {
+	fs := cmdLayoutDiff.flags()
+	dir := fs.String("dir", ".", "module `directory` inside the git repository")
+	arch := fs.String("arch", "amd64,386,arm64", "comma-separated `GOARCH`es whose layouts to compare")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errUsage
+	}
+	archs := strings.Split(*arch, ",")
+
+	var revs [2]map[string][]layout.ModuleStruct
+	for i, rev := range fs.Args() {
+		modDir, cleanup, err := checkout(ctx, *dir, rev)
+		if err != nil {
+			return err
+		}
+		revs[i], err = layout.AnalyzeModule(ctx, modDir, archs)
+		cleanup()
+		if err != nil {
+			return fmt.Errorf("%s: %v", rev, err)
+		}
+	}
+
+	// The structs of the first GOARCH give the order and the sensitive
+	// uses; each GOARCH is then compared in turn.
+	var order []string
+	olds := make(map[string]map[string]layout.ModuleStruct)
+	news := make(map[string]map[string]layout.ModuleStruct)
+	for _, a := range archs {
+		olds[a], news[a] = byKey(revs[0][a]), byKey(revs[1][a])
+	}
+	for _, s := range revs[1][archs[0]] {
+		order = append(order, s.Key())
+	}
+
+	changed, flagged, added := 0, 0, 0
+	for _, key := range order {
+		s := news[archs[0]][key]
+		if _, ok := olds[archs[0]][key]; !ok {
+			added++
+			continue
+		}
+		// Group the GOARCHes with identical changes onto one line.
+		var lines []string
+		byChanges := make(map[string][]string)
+		for _, a := range archs {
+			c := strings.Join(layout.Compare(olds[a][key].Struct, news[a][key].Struct), "; ")
+			if c == "" {
+				continue
+			}
+			if byChanges[c] == nil {
+				lines = append(lines, c)
+			}
+			byChanges[c] = append(byChanges[c], a)
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		changed++
+		uses := slices.Clone(olds[archs[0]][key].Uses)
+		for _, u := range s.Uses {
+			if !slices.Contains(uses, u) {
+				uses = append(uses, u)
+			}
+		}
+		mark := " "
+		if len(uses) > 0 {
+			flagged++
+			mark = "!"
+		}
+		fmt.Printf("%s %s (%s)", mark, key, s.Pos)
+		if len(uses) > 0 {
+			fmt.Printf(" [%s]", strings.Join(uses, ", "))
+		}
+		fmt.Println()
+		for _, c := range lines {
+			fmt.Printf("    %s: %s\n", strings.Join(byChanges[c], ", "), c)
+		}
+	}
+	removed := 0
+	for key := range olds[archs[0]] {
+		if _, ok := news[archs[0]][key]; !ok {
+			removed++
+		}
+	}
+	fmt.Printf("%d struct(s) changed layout, %d added, %d removed (%s)\n", changed, added, removed, strings.Join(archs, ", "))
+	if flagged > 0 {
+		return fmt.Errorf("%d changed struct(s) are used with encoding/binary, unsafe or shared memory", flagged)
+	}
+	return nil
+}
+
+func byKey(structs []layout.ModuleStruct) map[string]layout.ModuleStruct {
+	m := make(map[string]layout.ModuleStruct)
+	for _, s := range structs {
+		m[s.Key()] = s
+	}
+	return m
+}
+
+// checkout returns the directory of the module in dir as of rev, and a
+// function that removes it again. The revision "." is dir itself;
+// anything else is checked out with git worktree into a temporary
+// directory, at the same path inside the repository as dir.
+func checkout(ctx context.Context, dir, rev string) (modDir string, cleanup func(), err error) {
+	if rev == "." {
+		return dir, func() {}, nil
+	}
+	prefix, err := git(ctx, dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", nil, err
+	}
+	tmp, err := os.MkdirTemp("", "basics-layout-diff-")
+	if err != nil {
+		return "", nil, err
+	}
+	tree := filepath.Join(tmp, "tree")
+	if _, err := git(ctx, dir, "worktree", "add", "--detach", tree, rev); err != nil {
+		os.RemoveAll(tmp)
+		return "", nil, err
+	}
+	cleanup = func() {
+		// Remove the worktree even if ctx was cancelled, or git keeps a
+		// stale entry for it.
+		git(context.WithoutCancel(ctx), dir, "worktree", "remove", "--force", tree)
+		os.RemoveAll(tmp)
+	}
+	return filepath.Join(tree, prefix), cleanup, nil
+}
+
+// git runs git in dir and returns its trimmed output.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", errors.New("git " + args[0] + ": " + msg)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,149 @@
+// Command basics is the companion tool for the lessons in this module.
+//
+// Usage:
+//
+//	basics <command> [arguments]
+//
+// Run "basics help" for the list of commands. Commands that take a
+// module directory default to the current directory, so the usual way to
+// run the tool is from the module root:
+//
+//	go run ./cmd/basics trace pointer
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"basics/internal/buildtime"
+)
+
+// command is one subcommand of basics.
+type command struct {
+	name  string
+	args  string // argument synopsis for usage messages
+	short string // one-line description
+	run   func(ctx context.Context, args []string) error
+
+	// graceful means run returns promptly once ctx is canceled. Other
+	// commands run with a context that is never canceled, and SIGINT and
+	// SIGTERM keep their default action: they kill the process.
+	graceful bool
+}
+
+var commands = []*command{
+	cmdBench,
+	cmdBuildtime,
+	cmdCrossrun,
+	cmdDeps,
+	cmdDoctor,
+	cmdErrorcheck,
+	cmdEscape,
+	cmdExport,
+	cmdImport,
+	cmdLayout,
+	cmdLayoutDiff,
+	cmdPredict,
+	cmdRun,
+	cmdServe,
+	cmdSimilar,
+	cmdThreshold,
+	cmdTrace,
+	cmdUnkeyed,
+	cmdVersions,
+	cmdWatch,
+}
+
+// errUsage is returned by commands when their arguments are wrong; the
+// command's usage has already been printed.
+var errUsage = errors.New("usage")
+
+func main() {
+	// Builds timed by "basics buildtime" run this binary as their
+	// -toolexec wrapper.
+	if os.Getenv(buildtime.LogEnv) != "" {
+		os.Exit(buildtime.Wrap(os.Args[1:]))
+	}
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		usage()
+		return
+	}
+	name := os.Args[1]
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		os.Exit(run(c, os.Args[2:]))
+	}
+	fmt.Fprintf(os.Stderr, "basics: unknown command %q\n", name)
+	usage()
+	os.Exit(2)
+}
+
+// run runs c and returns the process exit code.
+//
+// A graceful command gets a context that is canceled on the first SIGINT
+// or SIGTERM. It must then stop starting new work, let in-flight work
+// finish or cancel it, remove its temporary directories and return;
+// returning nil means the shutdown was clean and the exit code is 0. A
+// second signal is not caught and kills the process.
+func run(c *command, args []string) int {
+	ctx := context.Background()
+	if c.graceful {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			stop()
+		}()
+	}
+
+	err := c.run(ctx, args)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	case ctx.Err() != nil && errors.Is(err, context.Canceled):
+		fmt.Fprintf(os.Stderr, "basics %s: interrupted\n", c.name)
+		return 130
+	}
+	fmt.Fprintf(os.Stderr, "basics %s: %v\n", c.name, err)
+	return 1
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: basics <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	tw := tabwriter.NewWriter(os.Stderr, 0, 8, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "\t%s\t%s\n", c.name, c.short)
+	}
+	tw.Flush()
+}
+
+// flags returns a flag set for c whose usage message shows the
+// command's synopsis.
+func (c *command) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: basics %s %s\n", c.name, c.args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses args with fs, mapping flag errors to errUsage.
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	return nil
+}
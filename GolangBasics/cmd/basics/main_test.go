@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// basicsBin is the basics binary the tests start as a subprocess.
+var basicsBin string
+
+func TestMain(m *testing.M) {
+	tmp, err := os.MkdirTemp("", "basics-test-")
+	if err != nil {
+		panic(err)
+	}
+	basicsBin = filepath.Join(tmp, "basics")
+	if out, err := exec.Command("go", "build", "-o", basicsBin, ".").CombinedOutput(); err != nil {
+		os.RemoveAll(tmp)
+		panic("go build: " + string(out))
+	}
+	code := m.Run()
+	os.RemoveAll(tmp)
+	os.Exit(code)
+}
+
+// moduleDir is the module holding the lessons, relative to this package.
+const moduleDir = "../.."
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"basics/internal/lesson"
+	"basics/internal/txtar"
+)
+
+var cmdPredict = &command{
+	name:     "predict",
+	args:     "[-dir dir] [-answers file] [-record file] lesson",
+	short:    "predict the output of each print statement of a lesson before seeing it",
+	graceful: true,
+}
+
+func init() {
+	cmdPredict.run = runPredict
+}
+
+// A prediction is the trainee's answer at one checkpoint.
+type prediction struct {
+	Checkpoint string   `json:"checkpoint"` // file:line
+	Src        string   `json:"src"`
+	Predicted  []string `json:"predicted"`
+	Output     []string `json:"output"` // normalized
+	Correct    bool     `json:"correct"`
+}
+
+// session is one run of predict, as recorded.
+type session struct {
+	Time        time.Time    `json:"time"`
+	Lesson      string       `json:"lesson"`
+	Score       int          `json:"score"`
+	Total       int          `json:"total"`
+	Predictions []prediction `json:"predictions"`
+}
+
+func runPredict(ctx context.Context, args []string) error {
+	fs := cmdPredict.flags()
+	dir := fs.String("dir", ".", "module `directory` containing the lessons")
+	answers := fs.String("answers", "", "read predictions from the txtar `file` instead of the terminal; each section is named file:line")
+	record := fs.String("record", "", "append the session to the JSON lines `file` (default basics/predictions.jsonl in the user config directory; none with -answers)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	lessons, err := lesson.Load(*dir)
+	if err != nil {
+		return err
+	}
+	l, err := lesson.Find(lessons, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	modGo, err := lesson.ModuleGoVersion(*dir)
+	if err != nil {
+		return err
+	}
+	if err := lesson.CheckVersion(l, modGo); err != nil {
+		return err
+	}
+	if err := lesson.CheckOS(l); err != nil {
+		return err
+	}
+
+	var next func(c lesson.Checkpoint, n int) ([]string, error)
+	if *answers != "" {
+		data, err := os.ReadFile(*answers)
+		if err != nil {
+			return err
+		}
+		next = answersFrom(txtar.Parse(data))
+	} else {
+		if *record == "" {
+			dir, err := os.UserConfigDir()
+			if err != nil {
+				return err
+			}
+			*record = filepath.Join(dir, "basics", "predictions.jsonl")
+		}
+		next = promptFrom(ctx, os.Stdin)
+	}
+
+	// The lesson runs once, instrumented, before the first question; its
+	// output is then revealed one checkpoint at a time.
+	src, cps, err := lesson.Instrument(l)
+	if err != nil {
+		return err
+	}
+	if len(cps) == 0 {
+		return fmt.Errorf("lesson %s has no print statements in %s", l.Name, l.Func)
+	}
+	b, err := lesson.NewOverlayBuild(ctx, *dir, map[string][]byte{l.File: src})
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	out, err := b.Run(ctx, l.Name)
+	if err != nil {
+		return fmt.Errorf("lesson %s: %v\n%s", l.Name, err, out)
+	}
+
+	s := session{Time: time.Now(), Lesson: l.Name}
+	for _, seg := range lesson.SplitCheckpoints(out) {
+		if seg.Checkpoint < 0 {
+			os.Stdout.Write(seg.Output)
+			continue
+		}
+		// Print("") and the like print nothing: there is nothing to predict
+		if len(seg.Output) == 0 {
+			continue
+		}
+		c := cps[seg.Checkpoint]
+		want := lines(lesson.Normalize(seg.Output))
+		fmt.Printf("\n%s  %s\n", c, c.Src)
+		got, err := next(c, len(want))
+		if errors.Is(err, io.EOF) {
+			fmt.Println()
+			break
+		}
+		if err != nil {
+			return err
+		}
+		p := prediction{Checkpoint: c.String(), Src: c.Src, Predicted: got, Output: want, Correct: sameOutput(got, want)}
+		s.Predictions = append(s.Predictions, p)
+		s.Total++
+		if p.Correct {
+			s.Score++
+			fmt.Println("  ✓")
+		} else {
+			fmt.Println("  ✗ it printed:")
+			for _, w := range want {
+				fmt.Println("    " + w)
+			}
+		}
+	}
+	if s.Total > 0 {
+		fmt.Printf("\nscore: %d/%d (%d%%)\n", s.Score, s.Total, 100*s.Score/s.Total)
+	}
+
+	if *record != "" && s.Total > 0 {
+		if err := appendSession(*record, s); err != nil {
+			return err
+		}
+	}
+	if *answers != "" && s.Score < s.Total {
+		return fmt.Errorf("%d of %d predictions wrong", s.Total-s.Score, s.Total)
+	}
+	return nil
+}
+
+// promptFrom returns a function asking the trainee for the n lines a
+// checkpoint prints. Lines are read in a goroutine, so that waiting for
+// an answer ends when ctx is canceled.
+func promptFrom(ctx context.Context, r io.Reader) func(lesson.Checkpoint, int) ([]string, error) {
+	type line struct {
+		text string
+		err  error
+	}
+	lines := make(chan line)
+	go func() {
+		sc := bufio.NewScanner(r)
+		for sc.Scan() {
+			lines <- line{text: sc.Text()}
+		}
+		err := sc.Err()
+		if err == nil {
+			err = io.EOF
+		}
+		lines <- line{err: err}
+		close(lines)
+	}()
+	return func(c lesson.Checkpoint, n int) ([]string, error) {
+		var got []string
+		for i := range n {
+			if n == 1 {
+				fmt.Print("? ")
+			} else {
+				fmt.Printf("? (%d/%d) ", i+1, n)
+			}
+			select {
+			case <-ctx.Done():
+				fmt.Println()
+				return nil, ctx.Err()
+			case l, ok := <-lines:
+				if !ok {
+					return nil, io.EOF
+				}
+				if l.err != nil {
+					return nil, l.err
+				}
+				got = append(got, l.text)
+			}
+		}
+		return got, nil
+	}
+}
+
+// answersFrom returns a function taking the predictions for each
+// checkpoint from the archive's sections named after it, in order, so a
+// print statement in a loop has one section per iteration. A checkpoint
+// without a section is answered with nothing, which is wrong.
+func answersFrom(ar *txtar.Archive) func(lesson.Checkpoint, int) ([]string, error) {
+	used := make(map[string]int)
+	return func(c lesson.Checkpoint, n int) ([]string, error) {
+		name := c.String()
+		k := 0
+		for _, f := range ar.Files {
+			if f.Name != name {
+				continue
+			}
+			if k == used[name] {
+				used[name]++
+				got := lines(f.Data)
+				for _, g := range got {
+					fmt.Println("? " + g)
+				}
+				return got, nil
+			}
+			k++
+		}
+		fmt.Println("? (no answer)")
+		return nil, nil
+	}
+}
+
+// lines splits output into lines, without the final newline.
+func lines(out []byte) []string {
+	return strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+}
+
+// sameOutput reports whether the prediction matches the normalized
+// output, line by line. Addresses in the prediction are normalized too,
+// so any address, or 0xADDR, matches one, and runs of spaces count as
+// one: println separates its arguments with a space of its own.
+func sameOutput(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		g := strings.Fields(string(lesson.Normalize([]byte(got[i]))))
+		if strings.Join(g, " ") != strings.Join(strings.Fields(want[i]), " ") {
+			return false
+		}
+	}
+	return true
+}
+
+// appendSession appends s to the JSON lines file.
+func appendSession(file string, s session) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(s); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
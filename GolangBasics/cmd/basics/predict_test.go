@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPredictAnswers runs predict on the pointer lesson with the answers
+// in testdata, right and then with one wrong, and checks the score and the
+// sessions it records.
+func TestPredictAnswers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a lesson")
+	}
+	answers, err := os.ReadFile(filepath.Join(moduleDir, "testdata", "predict_pointer.txtar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp := t.TempDir()
+	wrong := filepath.Join(tmp, "wrong.txtar")
+	if err := os.WriteFile(wrong, []byte(strings.Replace(string(answers), "After :  42", "After :  43", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	record := filepath.Join(tmp, "predictions.jsonl")
+
+	for _, tt := range []struct {
+		answers string
+		score   int
+		fail    string
+	}{
+		{filepath.Join(moduleDir, "testdata", "predict_pointer.txtar"), 5, ""},
+		{wrong, 4, "1 of 5 predictions wrong"},
+	} {
+		cmd := exec.Command(basicsBin, "predict", "-dir", moduleDir, "-answers", tt.answers, "-record", record, "pointer")
+		out, err := cmd.CombinedOutput()
+		if tt.fail == "" && err != nil {
+			t.Fatalf("predict -answers %s: %v\n%s", tt.answers, err, out)
+		}
+		if tt.fail != "" && (err == nil || !strings.Contains(string(out), tt.fail)) {
+			t.Errorf("predict -answers %s: %v, want %q\n%s", tt.answers, err, tt.fail, out)
+		}
+		if want := fmt.Sprintf("score: %d/5", tt.score); !strings.Contains(string(out), want) {
+			t.Errorf("predict -answers %s: output lacks %q\n%s", tt.answers, want, out)
+		}
+	}
+
+	data, err := os.ReadFile(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sessions []session
+	for _, line := range lines(data) {
+		var s session
+		if err := json.Unmarshal([]byte(line), &s); err != nil {
+			t.Fatalf("%s: %v", record, err)
+		}
+		sessions = append(sessions, s)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("%d sessions recorded, want 2", len(sessions))
+	}
+	want := []string{"pointers.go:23", "pointers.go:10", "pointers.go:26", "pointers.go:17", "pointers.go:30"}
+	for i, s := range sessions {
+		if s.Lesson != "pointer" || s.Total != 5 || s.Score != 5-i || len(s.Predictions) != 5 {
+			t.Errorf("session %d: lesson %s, score %d/%d with %d predictions; want pointer, %d/5 with 5", i, s.Lesson, s.Score, s.Total, len(s.Predictions), 5-i)
+			continue
+		}
+		for j, p := range s.Predictions {
+			if p.Checkpoint != want[j] {
+				t.Errorf("session %d: prediction %d at %s, want %s", i, j, p.Checkpoint, want[j])
+			}
+			if correct := i == 0 || j != 2; p.Correct != correct {
+				t.Errorf("session %d: prediction at %s correct = %v, want %v", i, p.Checkpoint, p.Correct, correct)
+			}
+		}
+	}
+	if p := sessions[1].Predictions[2]; len(p.Output) != 1 || !strings.HasPrefix(p.Output[0], "After :  42 0xADDR") {
+		t.Errorf("recorded output %q, want the normalized line printed", p.Output)
+	}
+}
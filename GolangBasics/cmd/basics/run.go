@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"basics/internal/lesson"
+)
+
+var cmdRun = &command{
+	name:     "run",
+	args:     "[-dir dir] [-update] [-v] [lesson...]",
+	short:    "run lessons and check their output against testdata/*.golden",
+	graceful: true,
+}
+
+func init() {
+	cmdRun.run = runRun
+}
+
+func runRun(ctx context.Context, args []string) error {
+	fs := cmdRun.flags()
+	dir := fs.String("dir", ".", "module `directory` containing the lessons")
+	update := fs.Bool("update", false, "rewrite the golden files with the current output")
+	verbose := fs.Bool("v", false, "print each lesson's output")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	lessons, err := selectLessons(*dir, fs.Args())
+	if err != nil {
+		return err
+	}
+	modGo, err := lesson.ModuleGoVersion(*dir)
+	if err != nil {
+		return err
+	}
+	b, err := lesson.NewBuild(ctx, *dir)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	failed := 0
+	for _, l := range lessons {
+		res, err := check(ctx, b, l, modGo, *update)
+		if err != nil {
+			return err
+		}
+		if *verbose {
+			os.Stdout.Write(res.output)
+		}
+		fmt.Print(res)
+		if res.status == "FAIL" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d lessons failed", failed, len(lessons))
+	}
+	return nil
+}
+
+// selectLessons returns the lessons named in names, or all lessons if
+// names is empty.
+func selectLessons(dir string, names []string) ([]lesson.Lesson, error) {
+	all, err := lesson.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	return pickLessons(all, names)
+}
+
+// pickLessons returns the lessons of all named in names, or all of them if
+// names is empty.
+func pickLessons(all []lesson.Lesson, names []string) ([]lesson.Lesson, error) {
+	if len(names) == 0 {
+		return all, nil
+	}
+	var ls []lesson.Lesson
+	for _, name := range names {
+		l, err := lesson.Find(all, name)
+		if err != nil {
+			return nil, err
+		}
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// result is the outcome of running one lesson against its golden file.
+type result struct {
+	lesson lesson.Lesson
+	status string // "ok", "SKIP" or "FAIL"
+	msg    string // why the lesson was skipped or failed
+	diff   string // difference from the golden file
+	output []byte
+}
+
+func (r result) String() string {
+	s := fmt.Sprintf("%-4s %s", r.status, r.lesson.Name)
+	switch {
+	case r.status == "ok" && r.msg != "":
+		s += " (" + r.msg + ")"
+	case r.msg != "":
+		s += ": " + r.msg
+	}
+	return s + "\n" + r.diff
+}
+
+// check runs l in b and compares its output with the golden file, or
+// rewrites the golden file if update is set. Lessons needing a newer Go
+// than modGo fail, and lessons needing a newer toolchain or another
+// operating system are skipped. The
+// error is only for problems that stop the whole run.
+func check(ctx context.Context, b *lesson.Build, l lesson.Lesson, modGo string, update bool) (result, error) {
+	res := result{lesson: l, status: "FAIL"}
+	var verr *lesson.VersionError
+	if err := lesson.CheckVersion(l, modGo); errors.As(err, &verr) {
+		if verr.What == "toolchain" {
+			res.status = "SKIP"
+		}
+		res.msg = err.Error()
+		return res, nil
+	}
+	if err := lesson.CheckOS(l); err != nil {
+		res.status, res.msg = "SKIP", err.Error()
+		return res, nil
+	}
+	out, err := b.Run(ctx, l.Name)
+	if ctx.Err() != nil {
+		return res, ctx.Err()
+	}
+	res.output = out
+	if err != nil {
+		res.msg = err.Error()
+		return res, nil
+	}
+	if update {
+		if err := lesson.Update(b.Dir, l.Name, out); err != nil {
+			return res, err
+		}
+		res.status, res.msg = "ok", "updated"
+		return res, nil
+	}
+	diff, err := lesson.Check(b.Dir, l.Name, out)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		res.msg = "no golden file (run with -update)"
+	case err != nil:
+		return res, err
+	case diff != "":
+		res.msg = "output differs from " + lesson.GoldenPath(b.Dir, l.Name)
+		res.diff = diff
+	default:
+		res.status = "ok"
+	}
+	return res, nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"basics/internal/interp"
+	"basics/internal/lesson"
+)
+
+var cmdServe = &command{
+	name:     "serve",
+	args:     "[-dir dir] [-addr host:port] [-grace duration] [-timeout duration]",
+	short:    "serve a local playground that runs and traces lessons",
+	graceful: true,
+}
+
+func init() {
+	cmdServe.run = runServe
+}
+
+func runServe(ctx context.Context, args []string) error {
+	fs := cmdServe.flags()
+	dir := fs.String("dir", ".", "module `directory` containing the lessons")
+	addr := fs.String("addr", "localhost:8080", "`address` to listen on")
+	grace := fs.Duration("grace", 10*time.Second, "how long to let in-flight runs finish on shutdown")
+	timeout := fs.Duration("timeout", 30*time.Second, "limit on a single lesson run")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	lessons, err := lesson.Load(*dir)
+	if err != nil {
+		return err
+	}
+
+	// Lesson runs must survive the shutdown signal long enough to drain,
+	// so they get their own context, canceled only when the grace period
+	// is over.
+	runCtx, cancelRuns := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelRuns()
+	b, err := lesson.NewBuild(ctx, *dir)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	pg := &playground{dir: *dir, lessons: lessons, build: b, timeout: *timeout}
+	srv := &http.Server{
+		Handler:     pg.handler(),
+		BaseContext: func(net.Listener) context.Context { return runCtx },
+	}
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("serving %d lessons on http://%s", len(lessons), ln.Addr())
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down, waiting up to %v for %d in-flight runs", *grace, pg.inFlight())
+	shutCtx, cancel := context.WithTimeout(context.Background(), *grace)
+	defer cancel()
+	if err := srv.Shutdown(shutCtx); err != nil {
+		cancelRuns()
+		srv.Close()
+		return fmt.Errorf("shutdown: %d runs still in flight after %v: %w", pg.inFlight(), *grace, err)
+	}
+	log.Printf("shut down cleanly")
+	return nil
+}
+
+// playground serves the lesson list, lesson output and traces.
+type playground struct {
+	dir     string
+	lessons []lesson.Lesson
+	build   *lesson.Build
+	timeout time.Duration
+
+	mu      sync.Mutex
+	running int
+}
+
+func (pg *playground) inFlight() int {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	return pg.running
+}
+
+func (pg *playground) track(delta int) {
+	pg.mu.Lock()
+	pg.running += delta
+	pg.mu.Unlock()
+}
+
+func (pg *playground) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", pg.index)
+	mux.HandleFunc("GET /run/{lesson}", pg.run)
+	mux.HandleFunc("GET /trace/{lesson}", pg.trace)
+	return mux
+}
+
+func (pg *playground) index(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, l := range pg.lessons {
+		fmt.Fprintf(w, "%-12s /run/%s  /trace/%s\n", l.Name, l.Name, l.Name)
+	}
+}
+
+func (pg *playground) run(w http.ResponseWriter, r *http.Request) {
+	l, err := lesson.Find(pg.lessons, r.PathValue("lesson"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	pg.track(1)
+	defer pg.track(-1)
+	ctx, cancel := context.WithTimeout(r.Context(), pg.timeout)
+	defer cancel()
+	out, err := pg.build.Run(ctx, l.Name)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, context.DeadlineExceeded) {
+			status = http.StatusGatewayTimeout
+		}
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "%s\n%s: %v\n", out, l.Name, err)
+		return
+	}
+	w.Write(out)
+}
+
+func (pg *playground) trace(w http.ResponseWriter, r *http.Request) {
+	l, err := lesson.Find(pg.lessons, r.PathValue("lesson"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	prog, err := interp.Load(pg.dir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// The interpreter runs in this goroutine, so it gets the same limits
+	// as a lesson binary: the timeout, and cancellation when the grace
+	// period ends.
+	pg.track(1)
+	defer pg.track(-1)
+	ctx, cancel := context.WithTimeout(r.Context(), pg.timeout)
+	defer cancel()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	tr, err := prog.Run(ctx, l.Func, w)
+	if tr != nil {
+		fmt.Fprint(w, "\nMemory flow:\n\n")
+		tr.WriteDiagram(w)
+	}
+	if err != nil {
+		fmt.Fprintf(w, "\n%s: %v\n", l.Name, err)
+	}
+}
@@ -0,0 +1,209 @@
+//go:build unix
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// waitStatus waits for cmd and returns how it exited.
+func waitStatus(t *testing.T, cmd *exec.Cmd) syscall.WaitStatus {
+	t.Helper()
+	err := cmd.Wait()
+	var ee *exec.ExitError
+	if err != nil && !errors.As(err, &ee) {
+		t.Fatal(err)
+	}
+	return cmd.ProcessState.Sys().(syscall.WaitStatus)
+}
+
+// TestServeDrains checks that serve, on SIGINT or SIGTERM, lets a lesson
+// run that is in flight finish and send its output, then exits 0.
+func TestServeDrains(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs lessons")
+	}
+	for _, sig := range []syscall.Signal{syscall.SIGINT, syscall.SIGTERM} {
+		t.Run(sig.String(), func(t *testing.T) {
+			cmd := exec.Command(basicsBin, "serve", "-dir", moduleDir, "-addr", "127.0.0.1:0", "-grace", "1m")
+			stderr, err := cmd.StderrPipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cmd.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer cmd.Process.Kill()
+
+			// "serving 11 lessons on http://127.0.0.1:41234"
+			sc := bufio.NewScanner(stderr)
+			var url string
+			for sc.Scan() {
+				if _, u, ok := strings.Cut(sc.Text(), " on "); ok {
+					url = u
+					break
+				}
+			}
+			if url == "" {
+				t.Fatal("serve did not report its address")
+			}
+			var log strings.Builder
+			logDone := make(chan struct{})
+			go func() {
+				io.Copy(&log, stderr)
+				close(logDone)
+			}()
+
+			// The preemption lesson runs for about a second.
+			type response struct {
+				status int
+				body   string
+				err    error
+			}
+			resc := make(chan response, 1)
+			go func() {
+				resp, err := http.Get(url + "/run/preemption")
+				if err != nil {
+					resc <- response{err: err}
+					return
+				}
+				body, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				resc <- response{status: resp.StatusCode, body: string(body), err: err}
+			}()
+			time.Sleep(300 * time.Millisecond)
+			if err := cmd.Process.Signal(sig); err != nil {
+				t.Fatal(err)
+			}
+
+			res := <-resc
+			if res.err != nil {
+				t.Fatalf("in-flight request failed: %v", res.err)
+			}
+			if res.status != http.StatusOK || !strings.Contains(res.body, "runtime.GC() waited") {
+				t.Errorf("in-flight request: status %d, body:\n%s", res.status, res.body)
+			}
+			ws := waitStatus(t, cmd)
+			<-logDone
+			if !ws.Exited() || ws.ExitStatus() != 0 {
+				t.Errorf("serve exited with %v, want status 0\n%s", ws, log.String())
+			}
+			for _, want := range []string{"for 1 in-flight runs", "shut down cleanly"} {
+				if !strings.Contains(log.String(), want) {
+					t.Errorf("serve log lacks %q:\n%s", want, log.String())
+				}
+			}
+		})
+	}
+}
+
+// TestInterrupted checks that a graceful command stops on the first
+// signal with exit status 130.
+func TestInterrupted(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the compiler")
+	}
+	for _, sig := range []syscall.Signal{syscall.SIGINT, syscall.SIGTERM} {
+		t.Run(sig.String(), func(t *testing.T) {
+			// threshold compiles a program per step of its binary search
+			cmd := exec.Command(basicsBin, "threshold")
+			var stderr strings.Builder
+			cmd.Stderr = &stderr
+			if err := cmd.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer cmd.Process.Kill()
+			time.Sleep(300 * time.Millisecond)
+			if err := cmd.Process.Signal(sig); err != nil {
+				t.Fatal(err)
+			}
+			ws := waitStatus(t, cmd)
+			if !ws.Exited() || ws.ExitStatus() != 130 || !strings.Contains(stderr.String(), "interrupted") {
+				t.Errorf("threshold exited with %v, want status 130\n%s", ws, stderr.String())
+			}
+		})
+	}
+}
+
+// TestPredictInterrupted checks that predict, waiting for an answer on
+// its standard input, stops on a signal with exit status 130 and removes
+// its build directory.
+func TestPredictInterrupted(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a lesson")
+	}
+	for _, sig := range []syscall.Signal{syscall.SIGINT, syscall.SIGTERM} {
+		t.Run(sig.String(), func(t *testing.T) {
+			tmp := t.TempDir()
+			cmd := exec.Command(basicsBin, "predict", "-dir", moduleDir, "-record", filepath.Join(tmp, "p.jsonl"), "pointer")
+			cmd.Env = append(os.Environ(), "TMPDIR="+tmp)
+			var stderr strings.Builder
+			cmd.Stderr = &stderr
+			stdin, err := cmd.StdinPipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer stdin.Close()
+			stdout, err := cmd.StdoutPipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cmd.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer cmd.Process.Kill()
+
+			// Wait for the first question: the lesson is built by then.
+			asked := make(chan bool, 1)
+			go func() {
+				r := bufio.NewReader(stdout)
+				var out strings.Builder
+				for {
+					b, err := r.ReadByte()
+					if err != nil {
+						asked <- false
+						return
+					}
+					out.WriteByte(b)
+					if strings.HasSuffix(out.String(), "? ") {
+						asked <- true
+						io.Copy(io.Discard, r)
+						return
+					}
+				}
+			}()
+			select {
+			case ok := <-asked:
+				if !ok {
+					t.Fatalf("predict exited before asking\n%s", stderr.String())
+				}
+			case <-time.After(time.Minute):
+				t.Fatal("predict did not ask a question")
+			}
+			if err := cmd.Process.Signal(sig); err != nil {
+				t.Fatal(err)
+			}
+			ws := waitStatus(t, cmd)
+			if !ws.Exited() || ws.ExitStatus() != 130 || !strings.Contains(stderr.String(), "interrupted") {
+				t.Errorf("predict exited with %v, want status 130\n%s", ws, stderr.String())
+			}
+			left, err := filepath.Glob(filepath.Join(tmp, "basics-lessons-*"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(left) > 0 {
+				t.Errorf("predict left %v behind", left)
+			}
+		})
+	}
+}
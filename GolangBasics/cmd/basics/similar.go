@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"basics/internal/similar"
+)
+
+var cmdSimilar = &command{
+	name:     "similar",
+	args:     "[-k n] [-w n] [-min fraction] [-base dir] [-regions n] [-width n] dir",
+	short:    "report exercise submissions that share normalized code",
+	graceful: true,
+}
+
+func init() {
+	cmdSimilar.run = runSimilar
+}
+
+// runSimilar compares every pair of submissions in a directory: each
+// subdirectory, or each .go file, is one submission. Pairs scoring at
+// least -min are printed with their longest shared regions side by side.
+func runSimilar(ctx context.Context, args []string) error {
+	fs := cmdSimilar.flags()
+	k := fs.Int("k", 12, "k-gram length in tokens: shorter shared code is ignored")
+	w := fs.Int("w", 8, "winnowing window: shared code of k+w-1 tokens is always found")
+	minScore := fs.Float64("min", 0.5, "report pairs sharing at least this `fraction` of either submission's fingerprints")
+	base := fs.String("base", "", "`directory` of starter code whose k-grams are not counted as shared")
+	regions := fs.Int("regions", 3, "shared regions to show per pair")
+	width := fs.Int("width", 48, "column width of the side-by-side excerpts, at least 2")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *k < 1 || *w < 1 || *width < 2 {
+		fs.Usage()
+		return errUsage
+	}
+	subs, err := similar.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	for _, s := range subs {
+		for _, err := range s.Errors {
+			fmt.Fprintf(os.Stderr, "warning: %v (compared token by token)\n", err)
+		}
+	}
+	opts := similar.Options{K: *k, W: *w}
+	if *base != "" {
+		starter, err := similar.LoadDir(*base)
+		if err != nil {
+			return err
+		}
+		opts.Base = similar.BaseHashes(starter, *k)
+	}
+
+	reported := 0
+	matches, err := similar.Compare(ctx, subs, opts)
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		if m.Score() < *minScore {
+			break
+		}
+		if reported > 0 {
+			fmt.Println()
+		}
+		reported++
+		fmt.Printf("%s ~ %s: %.0f%% of %s, %.0f%% of %s (%d shared fingerprints)\n",
+			m.A.Name, m.B.Name, 100*m.ScoreA, m.A.Name, 100*m.ScoreB, m.B.Name, m.Shared)
+		for i, r := range m.Regions {
+			if i == *regions {
+				fmt.Printf("  ... %d more region(s)\n", len(m.Regions)-i)
+				break
+			}
+			fmt.Printf("  %s/%s ~ %s/%s (%d tokens)\n", m.A.Name, r.A, m.B.Name, r.B, r.Tokens)
+			printExcerpts(m.Lines(r), m.A.Files[r.A.File], m.B.Files[r.B.File], *width)
+		}
+	}
+	fmt.Printf("%d submission(s), %d pair(s), %d at or above %.0f%%\n", len(subs), len(matches), reported, 100**minScore)
+	return nil
+}
+
+// printExcerpts prints the aligned lines of a shared region side by side.
+func printExcerpts(rows [][2]int, a, b []string, width int) {
+	for _, row := range rows {
+		var left, right string
+		if row[0] > 0 {
+			left = column(a[row[0]-1], width)
+		}
+		if row[1] > 0 {
+			right = column(b[row[1]-1], width)
+		}
+		line := fmt.Sprintf("    %s%s | %s", left, strings.Repeat(" ", width-utf8.RuneCountInString(left)), right)
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
+
+// column expands tabs in line and cuts it to width runes, the last one an
+// ellipsis if it was longer. width is at least 2.
+func column(line string, width int) string {
+	line = strings.ReplaceAll(line, "\t", "    ")
+	if utf8.RuneCountInString(line) > width {
+		line = string([]rune(line)[:width-1]) + "…"
+	}
+	return line
+}
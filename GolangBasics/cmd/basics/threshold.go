@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"text/tabwriter"
+
+	"basics/internal/escape"
+)
+
+var cmdThreshold = &command{
+	name:     "threshold",
+	args:     "[-limit bytes]",
+	short:    "find the sizes at which the compiler moves allocations to the heap",
+	graceful: true,
+}
+
+func init() {
+	cmdThreshold.run = runThreshold
+}
+
+func runThreshold(ctx context.Context, args []string) error {
+	fs := cmdThreshold.flags()
+	limit := fs.Int64("limit", 64<<20, "largest size in `bytes` to try")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || *limit <= 0 {
+		fs.Usage()
+		return errUsage
+	}
+	ts, err := escape.FindThresholds(ctx, *limit)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Stack allocation limits for %s %s/%s:\n\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "Construct\tStack up to\tHeap from\tBuilds")
+	for _, t := range ts {
+		heap := fmt.Sprintf("never (up to %s)", bytesize(*limit))
+		if t.Heap > 0 {
+			heap = bytesize(t.Heap)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", t.Construct.Name, bytesize(t.Stack), heap, t.Probes)
+	}
+	return tw.Flush()
+}
+
+// bytesize formats n in bytes, with a binary unit when n is a whole
+// number of KiB or MiB.
+func bytesize(n int64) string {
+	switch {
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%d B (%d MiB)", n, n>>20)
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return fmt.Sprintf("%d B (%d KiB)", n, n>>10)
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"basics/internal/interp"
+	"basics/internal/lesson"
+)
+
+var cmdTrace = &command{
+	name:     "trace",
+	args:     "[-dir dir] [-flow=false] [lesson]",
+	short:    "run a lesson in the tracing interpreter and print its memory flow",
+	graceful: true,
+}
+
+func init() {
+	cmdTrace.run = runTrace
+}
+
+func runTrace(ctx context.Context, args []string) error {
+	fs := cmdTrace.flags()
+	dir := fs.String("dir", ".", "module `directory` containing the lessons")
+	flow := fs.Bool("flow", true, "print the memory-flow diagram after the output")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return errUsage
+	}
+	name := "main"
+	if fs.NArg() == 1 {
+		name = fs.Arg(0)
+	}
+	lessons, err := lesson.Load(*dir)
+	if err != nil {
+		return err
+	}
+	l, err := lesson.Find(lessons, name)
+	if err != nil {
+		return err
+	}
+	prog, err := interp.Load(*dir)
+	if err != nil {
+		return err
+	}
+	tr, err := prog.Run(ctx, l.Func, os.Stdout)
+	if tr != nil && *flow {
+		fmt.Println()
+		fmt.Println("Memory flow:")
+		fmt.Println()
+		if werr := tr.WriteDiagram(os.Stdout); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"basics/internal/lesson"
+	"basics/internal/unkeyed"
+)
+
+var cmdUnkeyed = &command{
+	name:     "unkeyed",
+	args:     "[-dir dir] [-diff | -fix]",
+	short:    "find struct literals without field keys, test files included, and optionally add them",
+	graceful: true,
+}
+
+func init() {
+	cmdUnkeyed.run = runUnkeyed
+}
+
+func runUnkeyed(ctx context.Context, args []string) error {
+	fs := cmdUnkeyed.flags()
+	dir := fs.String("dir", ".", "module root `directory`")
+	diff := fs.Bool("diff", false, "print the keyed rewrite of each file instead of listing literals")
+	fix := fs.Bool("fix", false, "rewrite the files in place")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	root, err := filepath.Abs(*dir)
+	if err != nil {
+		return err
+	}
+	files, err := unkeyed.Find(ctx, root)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, f := range files {
+		n += len(f.Literals)
+		rel, err := filepath.Rel(root, f.Path)
+		if err != nil {
+			rel = f.Path
+		}
+		if !*diff && !*fix {
+			for _, l := range f.Literals {
+				l.Pos.Filename = rel
+				fmt.Printf("%s: unkeyed %s literal (keys: %s)\n", l.Pos, l.Type, strings.Join(l.Keys, ", "))
+			}
+			continue
+		}
+		src, err := f.Rewrite()
+		if err != nil {
+			return fmt.Errorf("%s: %v", rel, err)
+		}
+		if *fix {
+			if err := os.WriteFile(f.Path, src, 0o644); err != nil {
+				return err
+			}
+			fmt.Printf("%s: keyed %d literals\n", rel, len(f.Literals))
+			continue
+		}
+		old, err := os.ReadFile(f.Path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("--- %s\n+++ %s (keyed)\n%s", rel, rel, changedLines(lesson.Diff(old, src)))
+	}
+	if n > 0 && !*fix {
+		return fmt.Errorf("%d unkeyed struct literals", n)
+	}
+	return nil
+}
+
+// changedLines keeps only the added and removed lines of a diff.
+func changedLines(diff string) string {
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "+ ") {
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
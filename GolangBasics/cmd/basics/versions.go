@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/version"
+	"os"
+	"path/filepath"
+
+	"basics/internal/langver"
+	"basics/internal/lesson"
+)
+
+var cmdVersions = &command{
+	name:  "versions",
+	args:  "[-dir dir] [-check | -update]",
+	short: "find the oldest Go version each lesson compiles with",
+}
+
+func init() {
+	cmdVersions.run = runVersions
+}
+
+func runVersions(ctx context.Context, args []string) error {
+	fs := cmdVersions.flags()
+	dir := fs.String("dir", ".", "module `directory` containing the lessons")
+	check := fs.Bool("check", false, "compare the report with testdata/versions.golden")
+	update := fs.Bool("update", false, "rewrite testdata/versions.golden")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	lessons, err := lesson.Load(*dir)
+	if err != nil {
+		return err
+	}
+	modGo, err := lesson.ModuleGoVersion(*dir)
+	if err != nil {
+		return err
+	}
+	reports, err := langver.Analyze(*dir, lessons, modGo)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	var wrong []string
+	for _, r := range reports {
+		fmt.Fprintf(&buf, "%s: needs %s, declares %s\n", r.Lesson.Name, r.Min, r.Lesson.MinVersion())
+		for _, st := range r.Steps {
+			if len(st.Errors) > 0 {
+				fmt.Fprintf(&buf, "  with types.Config{GoVersion: %q}:\n", st.Version)
+				for _, p := range st.Errors {
+					fmt.Fprintf(&buf, "    %v\n", p)
+				}
+			}
+			if len(st.API) > 0 {
+				fmt.Fprintf(&buf, "  with the standard library of %s:\n", st.Version)
+				for _, p := range st.API {
+					fmt.Fprintf(&buf, "    %v\n", p)
+				}
+			}
+		}
+		if version.Compare(r.Min, r.Lesson.MinVersion()) > 0 {
+			wrong = append(wrong, fmt.Sprintf("lesson %s needs %s but declares %s", r.Lesson.Name, r.Min, r.Lesson.MinVersion()))
+		}
+	}
+
+	golden := filepath.Join(*dir, "testdata", "versions.golden")
+	switch {
+	case *update:
+		if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+			return err
+		}
+	case *check:
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			return err
+		}
+		if diff := lesson.Diff(want, buf.Bytes()); diff != "" {
+			return fmt.Errorf("report differs from %s:\n%s", golden, diff)
+		}
+	default:
+		os.Stdout.Write(buf.Bytes())
+	}
+	for _, w := range wrong {
+		fmt.Fprintln(os.Stderr, w)
+	}
+	if len(wrong) > 0 {
+		return fmt.Errorf("%d lessons declare too old a Go version", len(wrong))
+	}
+	if *check {
+		fmt.Println("ok   versions")
+	}
+	return nil
+}
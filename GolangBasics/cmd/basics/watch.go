@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"basics/internal/lesson"
+)
+
+var cmdWatch = &command{
+	name:     "watch",
+	args:     "[-dir dir] [-interval duration] [lesson...]",
+	short:    "re-run lessons and their golden checks whenever a source file changes",
+	graceful: true,
+}
+
+func init() {
+	cmdWatch.run = runWatch
+}
+
+// runWatch polls the module for changes until interrupted. Polling needs
+// nothing but os.Stat, and a module of lessons is small enough to stat
+// every file a few times a second.
+func runWatch(ctx context.Context, args []string) error {
+	fs := cmdWatch.flags()
+	dir := fs.String("dir", ".", "module `directory` containing the lessons")
+	interval := fs.Duration("interval", 500*time.Millisecond, "how often to look for changes")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	names := fs.Args()
+	all, err := lesson.Scan(*dir)
+	if err != nil {
+		return err
+	}
+	if _, err := pickLessons(all, names); err != nil {
+		return err
+	}
+
+	snap, err := snapshot(*dir)
+	if err != nil {
+		return err
+	}
+	if err := watchRun(ctx, *dir, names, nil); err != nil {
+		return err
+	}
+	fmt.Printf("watching %s (Ctrl-C to stop)\n", *dir)
+	tick := time.NewTicker(*interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick.C:
+		}
+		next, err := snapshot(*dir)
+		if err != nil {
+			return err
+		}
+		changed := changedFiles(snap, next)
+		if len(changed) == 0 {
+			continue
+		}
+		// Editors often write a file in several steps; wait until it
+		// stops changing before building.
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-tick.C:
+			}
+			again, err := snapshot(*dir)
+			if err != nil {
+				return err
+			}
+			more := changedFiles(next, again)
+			if len(more) == 0 {
+				break
+			}
+			changed = append(changed, more...)
+			next = again
+		}
+		snap = next
+		fmt.Printf("\n%s changed: %s\n", time.Now().Format("15:04:05"), strings.Join(compactFiles(changed), ", "))
+		if err := watchRun(ctx, *dir, names, changed); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// fileState is what polling compares to notice a change.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// snapshot returns the state of the .go files, README files and
+// testdata files under dir, keyed by path relative to dir.
+func snapshot(dir string) (map[string]fileState, error) {
+	snap := make(map[string]fileState)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		inTestdata := filepath.Base(filepath.Dir(path)) == "testdata"
+		if !inTestdata && !strings.HasSuffix(name, ".go") && !strings.HasPrefix(name, "README") || name == lesson.RegistryFile {
+			return nil // watchRun generates its own lessons table
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // removed while walking
+		}
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		snap[rel] = fileState{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return snap, err
+}
+
+// changedFiles returns the files added, removed or modified between two
+// snapshots.
+func changedFiles(old, cur map[string]fileState) []string {
+	var changed []string
+	for name, st := range cur {
+		if o, ok := old[name]; !ok || o != st {
+			changed = append(changed, name)
+		}
+	}
+	for name := range old {
+		if _, ok := cur[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+func compactFiles(files []string) []string {
+	files = slices.Clone(files)
+	slices.Sort(files)
+	return slices.Compact(files)
+}
+
+// watchRun rebuilds the lessons and runs those affected by the changed
+// files: the lessons declared in a changed file or checked against a
+// changed golden file, or every lesson if any other file changed. A nil
+// changed list means the first run, which runs every selected lesson.
+func watchRun(ctx context.Context, dir string, names, changed []string) error {
+	// The lessons table is generated from the annotations as they are now
+	// and compiled in through an overlay, so that lessons_gen.go in the
+	// module is left alone until "go generate". A half-edited annotation
+	// is not fatal: report it and wait for the next change.
+	all, err := lesson.Scan(dir)
+	if err != nil {
+		fmt.Printf("FAIL lesson annotations:\n%v\n", err)
+		return nil
+	}
+	registry, err := lesson.Generate(all)
+	if err != nil {
+		return err
+	}
+	lessons, err := pickLessons(all, names)
+	if err != nil {
+		fmt.Printf("FAIL lessons table: %v\n", err)
+		return nil
+	}
+	lessons = affected(dir, lessons, changed)
+	if len(lessons) == 0 {
+		fmt.Println("no lessons affected")
+		return nil
+	}
+	modGo, err := lesson.ModuleGoVersion(dir)
+	if err != nil {
+		fmt.Printf("FAIL go.mod: %v\n", err)
+		return nil
+	}
+	start := time.Now()
+	b, err := lesson.NewOverlayBuild(ctx, dir, map[string][]byte{filepath.Join(dir, lesson.RegistryFile): registry})
+	var berr *lesson.BuildError
+	if errors.As(err, &berr) {
+		errs := berr.Errors()
+		fmt.Printf("FAIL build: %d compile error(s)\n", len(errs))
+		for _, e := range errs {
+			fmt.Printf("  %s\n", strings.ReplaceAll(e.String(), "\n\t", "\n      "))
+		}
+		if len(errs) == 0 {
+			os.Stdout.Write(berr.Output)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	var passed, failed, skipped int
+	for _, l := range lessons {
+		res, err := check(ctx, b, l, modGo, false)
+		if err != nil {
+			return err
+		}
+		switch res.status {
+		case "ok":
+			passed++
+			continue
+		case "SKIP":
+			skipped++
+		default:
+			failed++
+		}
+		res.diff = truncateLines(changedLines(res.diff), 10)
+		fmt.Print(res)
+	}
+	summary := fmt.Sprintf("%d ok, %d failed", passed, failed)
+	if skipped > 0 {
+		summary += fmt.Sprintf(", %d skipped", skipped)
+	}
+	fmt.Printf("%s: %s (%v)\n", lessonNames(lessons), summary, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// affected returns the lessons that a change to the files in changed can
+// alter. Changing a file that declares lessons, or a lesson's golden
+// file, re-runs only those lessons, and other testdata files re-run none;
+// changing anything else, such as shared code, lessons.go or README,
+// re-runs them all.
+func affected(dir string, lessons []lesson.Lesson, changed []string) []lesson.Lesson {
+	if changed == nil {
+		return lessons
+	}
+	declares := make(map[string]bool)
+	for _, l := range lessons {
+		rel, _ := filepath.Rel(dir, l.File)
+		declares[rel] = true
+	}
+	files := make(map[string]bool)
+	for _, f := range changed {
+		if !declares[f] && filepath.Base(filepath.Dir(f)) != "testdata" {
+			return lessons
+		}
+		files[f] = true
+	}
+	var ls []lesson.Lesson
+	for _, l := range lessons {
+		rel, _ := filepath.Rel(dir, l.File)
+		golden, _ := filepath.Rel(dir, lesson.GoldenPath(dir, l.Name))
+		if files[rel] || files[golden] {
+			ls = append(ls, l)
+		}
+	}
+	return ls
+}
+
+func lessonNames(lessons []lesson.Lesson) string {
+	var names []string
+	for _, l := range lessons {
+		names = append(names, l.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+// truncateLines returns the first n lines of s, noting how many were cut.
+func truncateLines(s string, n int) string {
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= n {
+		return s
+	}
+	return strings.Join(lines[:n], "") + fmt.Sprintf("  ... %d more lines\n", len(lines)-n)
+}
@@ -0,0 +1,67 @@
+// Command lessongen writes lessons_gen.go, the lessons table of the
+// module's root package, from annotations in lesson functions' doc
+// comments:
+//
+//	// lesson: pointer section=pointers--reference-semantics
+//	func pointer() {
+//
+// It fails if a lesson function has parameters or results, if a name is
+// used twice, or if a section is not a heading anchor of README.md. It is
+// run by go generate from the root package:
+//
+//	//go:generate go run ./cmd/lessongen
+//
+// With -check, it only reports whether lessons_gen.go is up to date.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"basics/internal/lesson"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "`directory` of the lessons package")
+	check := flag.Bool("check", false, "fail if the registry is out of date instead of writing it")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: lessongen [-dir dir] [-check]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(*dir, *check); err != nil {
+		fmt.Fprintln(os.Stderr, "lessongen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string, check bool) error {
+	if !check {
+		_, err := lesson.WriteRegistry(dir)
+		return err
+	}
+	lessons, err := lesson.Scan(dir)
+	if err != nil {
+		return err
+	}
+	src, err := lesson.Generate(lessons)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, lesson.RegistryFile)
+	old, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(old, src) {
+		return fmt.Errorf("%s is out of date: run go generate", path)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Devirtualization and interface call cost
+
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+type shape interface {
+	Area() float64
+}
+
+type rect struct {
+	width, height float64
+}
+
+func (r rect) Area() float64 {
+	return r.width * r.height
+}
+
+// Interface call: inside this function s could hold any shape, so s.Area()
+// must look the method up in the itab at run time
+//
+//go:noinline
+func areaOf(s shape) float64 {
+	return s.Area()
+}
+
+// Direct call: the compiler knows the method, and can inline it
+//
+//go:noinline
+func areaDirect(r rect) float64 {
+	return r.Area()
+}
+
+// Devirtualized call: s is an interface, but the compiler can see that it
+// always holds a rect, so it rewrites s.Area() as rect.Area(s) ("-m" reports
+// "devirtualizing s.Area to rect")
+//
+//go:noinline
+func areaDevirt(r rect) float64 {
+	var s shape = r
+	return s.Area()
+}
+
+// lesson: devirt section=devirtualization--interface-call-cost goVersion=go1.1
+func devirt() {
+	r := rect{width: 3, height: 4}
+	fmt.Println("direct:", areaDirect(r))
+	fmt.Println("interface:", areaOf(r))
+	fmt.Println("devirtualized:", areaDevirt(r))
+
+	// Passing a struct like ex2 through an interface: the value is copied into
+	// the interface. If the interface escapes (fmt.Println's arguments do), the
+	// copy is allocated on the heap: "-m" reports "ex2 escapes to heap"
+	type example struct {
+		pi      float32
+		radius  int16
+		length  int16
+		breadth int16
+		isValid bool
+	}
+	ex2 := example{pi: 3.14, radius: 5, length: 10, breadth: 15, isValid: true}
+	fmt.Println(ex2)
+
+	if !benchmarking() {
+		return
+	}
+	bench := newBenchTable(false, true, "Call")
+	bench.run("direct", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			floatSink = areaDirect(r)
+		}
+	})
+	bench.run("interface", func(b *testing.B) {
+		var s shape = r
+		for i := 0; i < b.N; i++ {
+			floatSink = areaOf(s)
+		}
+	})
+	bench.run("devirtualized", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			floatSink = areaDevirt(r)
+		}
+	})
+	bench.run("ex2 to any", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			anySink = ex2
+		}
+	})
+	bench.flush()
+}
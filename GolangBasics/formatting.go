@@ -0,0 +1,120 @@
+// Formatting verbs and custom formatters
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// example is main()'s struct, declared at package level so it can have
+// methods
+type example struct {
+	pi      float32
+	radius  int16
+	length  int16
+	breadth int16
+	isValid bool
+}
+
+// plain has the same fields as example but none of its methods, so fmt
+// prints it with the default formats
+type plain example
+
+// String implements fmt.Stringer: used by %v and %s, and by Println
+func (e example) String() string {
+	return fmt.Sprintf("pi=%.2f radius=%d length=%d breadth=%d valid=%t", e.pi, e.radius, e.length, e.breadth, e.isValid)
+}
+
+// GoString implements fmt.GoStringer: used by %#v
+func (e example) GoString() string {
+	return fmt.Sprintf("example{pi: %v, radius: %d, length: %d, breadth: %d, isValid: %t}", e.pi, e.radius, e.length, e.breadth, e.isValid)
+}
+
+// Format implements fmt.Formatter. A Formatter takes over every verb, so
+// String and GoString are only used if Format calls them: %v and %s print
+// String (padded to the width), %#v prints GoString, and %+v prints the
+// labelled layout view
+func (e example) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && f.Flag('#'):
+		fmt.Fprint(f, e.GoString())
+	case verb == 'v' && f.Flag('+'):
+		fmt.Fprint(f, e.layout())
+	case verb == 'v' || verb == 's':
+		s := e.String()
+		if w, ok := f.Width(); ok && len(s) < w {
+			pad := strings.Repeat(" ", w-len(s))
+			if f.Flag('-') {
+				s += pad
+			} else {
+				s = pad + s
+			}
+		}
+		fmt.Fprint(f, s)
+	default:
+		// The same form fmt uses for a verb that does not fit the value
+		fmt.Fprintf(f, "%%!%c(example=%s)", verb, e.String())
+	}
+}
+
+// layout shows where each field lives in memory and the padding between
+func (e example) layout() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "example (%d bytes, align %d)\n", unsafe.Sizeof(e), unsafe.Alignof(e))
+	fields := []struct {
+		name   string
+		offset uintptr
+		size   uintptr
+		value  any
+	}{
+		{name: "pi", offset: unsafe.Offsetof(e.pi), size: unsafe.Sizeof(e.pi), value: e.pi},
+		{name: "radius", offset: unsafe.Offsetof(e.radius), size: unsafe.Sizeof(e.radius), value: e.radius},
+		{name: "length", offset: unsafe.Offsetof(e.length), size: unsafe.Sizeof(e.length), value: e.length},
+		{name: "breadth", offset: unsafe.Offsetof(e.breadth), size: unsafe.Sizeof(e.breadth), value: e.breadth},
+		{name: "isValid", offset: unsafe.Offsetof(e.isValid), size: unsafe.Sizeof(e.isValid), value: e.isValid},
+	}
+	end := uintptr(0)
+	for _, f := range fields {
+		if f.offset > end {
+			fmt.Fprintf(&b, "  +%-3d %-8s %d byte(s)\n", end, "padding", f.offset-end)
+		}
+		fmt.Fprintf(&b, "  +%-3d %-8s %-8T %v\n", f.offset, f.name, f.value, f.value)
+		end = f.offset + f.size
+	}
+	if size := unsafe.Sizeof(e); size > end {
+		fmt.Fprintf(&b, "  +%-3d %-8s %d byte(s)", end, "padding", size-end)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// lesson: formatting section=formatting-verbs--custom-formatters
+func formatting() {
+
+	// Default formats: a struct without methods
+	ex2 := plain{pi: 3.14, radius: 5, length: 10, breadth: 15, isValid: true}
+	fmt.Printf("%%v   %v\n", ex2)  // field values
+	fmt.Printf("%%+v  %+v\n", ex2) // field names and values
+	fmt.Printf("%%#v  %#v\n", ex2) // Go syntax, with the type
+	fmt.Printf("%%T   %T\n", ex2)  // the type
+	fmt.Printf("%%p   %p\n", &ex2) // a pointer's address (0xADDR in the golden file)
+
+	// Width and precision: %[width].[precision]verb
+	fmt.Printf("|%f|%.2f|%8.2f|%-8.2f|%08.2f|\n", ex2.pi, ex2.pi, ex2.pi, ex2.pi, ex2.pi)
+	fmt.Printf("|%d|%5d|%-5d|%05d|%+d|\n", ex2.radius, ex2.radius, ex2.radius, ex2.radius, ex2.radius)
+	fmt.Printf("|%x|%X|%o|%b|%#x|\n", ex2.breadth, ex2.breadth, ex2.breadth, ex2.breadth, ex2.breadth)
+	fmt.Printf("|%e|%g|%.3g|\n", ex2.pi, ex2.pi, ex2.pi)
+	fmt.Printf("|%t|%q|%10s|%-10s|%.3s|\n", ex2.isValid, "Alice", "Alice", "Alice", "Alice")
+	fmt.Printf("|%*d|%-*d|\n", 6, ex2.length, 6, ex2.length) // width from an argument
+
+	// The same values with Stringer, GoStringer and Formatter
+	ex := example(ex2)
+	fmt.Println(ex)
+	fmt.Printf("%%v   %v\n", ex)
+	fmt.Printf("%%#v  %#v\n", ex)
+	fmt.Printf("%%T   %T\n", ex)
+	fmt.Printf("%%d   %d\n", ex)
+	fmt.Printf("|%60v|\n", ex)
+	fmt.Printf("%%+v  %+v\n", ex)
+}
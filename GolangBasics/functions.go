@@ -0,0 +1,176 @@
+// Functions: results, variadic parameters, function values, methods and recursion
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Multiple results: the caller receives both, or discards one with _
+func divide(a, b int) (int, error) {
+	if b == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return a / b, nil
+}
+
+// Named results are variables, set to their zero values on entry. A bare
+// return returns their current values
+func minMax(xs []int) (lo, hi int) {
+	if len(xs) == 0 {
+		return
+	}
+	lo, hi = xs[0], xs[0]
+	for _, x := range xs[1:] {
+		lo, hi = min(lo, x), max(hi, x)
+	}
+	return
+}
+
+// A deferred function runs after the return values are set and can still
+// change named results: this turns the panic of an integer division by
+// zero into an error
+func safeDivide(a, b int) (q int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("recovered: %v", r)
+		}
+	}()
+	return a / b, nil
+}
+
+// Variadic parameter: inside the function nums is a []int. For sum(1, 2, 3)
+// the compiler builds a new slice; it does not escape, so it lives on the
+// caller's stack ("-m": "... argument does not escape")
+func sum(nums ...int) int { // ESCAPE "nums does not escape"
+	total := 0
+	for _, n := range nums {
+		total += n
+	}
+	return total
+}
+
+// scale writes to its variadic slice. Called as scale(2, s...), nums is s
+// itself, not a copy, so the caller sees the change
+func scale(factor int, nums ...int) {
+	for i := range nums {
+		nums[i] *= factor
+	}
+}
+
+// Function types: any func(int, int) int is a binop, so functions can be
+// stored, passed and returned like other values
+type binop func(a, b int) int
+
+func fold(f binop, init int, xs ...int) int {
+	acc := init
+	for _, x := range xs {
+		acc = f(acc, x)
+	}
+	return acc
+}
+
+// adder returns a closure over n. The closure outlives adder's frame, so
+// it is allocated on the heap with its own copy of n ("-m": "func literal
+// escapes to heap"); n is never reassigned, so it is captured by value
+// and does not move to the heap itself
+func adder(n int) binop {
+	return func(a, b int) int { return a + b + n } // ESCAPE "func literal escapes to heap"
+}
+
+type tally struct {
+	n int
+}
+
+func (t *tally) add(k int) { t.n += k } // INLINE ESCAPE "t does not escape"
+
+func (t tally) count() int { return t.n }
+
+// Recursion: each call gets a new stack frame; Go does not eliminate tail
+// calls, and goroutine stacks grow as deep recursion needs
+func fib(n int) int {
+	if n < 2 {
+		return n
+	}
+	return fib(n-1) + fib(n-2)
+}
+
+// hanoi returns the moves for n disks from one peg to another
+func hanoi(n int, from, to, via string) []string {
+	if n == 0 {
+		return nil
+	}
+	moves := hanoi(n-1, from, via, to)
+	moves = append(moves, from+"→"+to)
+	return append(moves, hanoi(n-1, via, to, from)...)
+}
+
+// lesson: functions section=functions goVersion=go1.21
+func functions() {
+	// Multiple and named results
+	q, err := divide(7, 2)
+	fmt.Println("divide(7, 2):", q, err)
+	_, err = divide(1, 0)
+	fmt.Println("divide(1, 0):", err)
+	lo, hi := minMax([]int{4, 9, 1, 7})
+	fmt.Println("minMax:", lo, hi)
+	fmt.Println("minMax(nil):", fmt.Sprint(minMax(nil)))
+	fmt.Println("safeDivide(1, 0):", fmt.Sprint(safeDivide(1, 0)))
+
+	// Variadic parameters and the s... spread
+	fmt.Println("sum():", sum(), "sum(1, 2, 3):", sum(1, 2, 3))
+	s := []int{1, 2, 3}
+	fmt.Println("sum(s...):", sum(s...))
+	scale(10, s...) // nums aliases s
+	fmt.Println("after scale(10, s...):", s)
+	scale(10, s[0], s[1], s[2]) // nums is a new slice // ESCAPE `\.\.\. argument does not escape`
+	fmt.Println("after scale(10, s[0], s[1], s[2]):", s)
+	// Appending to a spread slice: the append writes into buf's spare
+	// capacity, in the caller's backing array
+	buf := make([]int, 2, 4)
+	grow := func(nums ...int) []int { return append(nums, 99) }
+	grown := grow(buf...)
+	fmt.Println("buf[:3] after grow(buf...):", buf[:3], "grown:", grown)
+
+	// Function types as values
+	ops := map[string]binop{
+		"add": func(a, b int) int { return a + b }, // ESCAPE "func literal escapes to heap"
+		"mul": func(a, b int) int { return a * b }, // ESCAPE "func literal escapes to heap"
+	}
+	fmt.Println("fold add:", fold(ops["add"], 0, 1, 2, 3, 4), "fold mul:", fold(ops["mul"], 1, 1, 2, 3, 4))
+	fmt.Println("fold adder(100):", fold(adder(100), 0, 1, 2))
+	var none binop
+	fmt.Println("nil binop:", none == nil)
+
+	// Method values bind their receiver when they are evaluated; method
+	// expressions take it as the first argument
+	t := tally{}
+	add := t.add     // (&t).add: later calls change t // ESCAPE "t.add does not escape"
+	count := t.count // copies t now: later changes are not seen // ESCAPE "t.count does not escape"
+	add(5)
+	fmt.Println("t.n:", t.n, "method value count():", count(), "t.count():", t.count())
+	addExpr := (*tally).add  // func(*tally, int)
+	countExpr := tally.count // func(tally) int
+	addExpr(&t, 1)
+	fmt.Println("method expressions:", countExpr(t), fmt.Sprintf("%T", countExpr))
+
+	// Recursion, including a recursive closure, which must be declared
+	// before it is assigned so that its body can refer to it
+	fmt.Println("fib(20):", fib(20))
+	fmt.Println("hanoi(3):", strings.Join(hanoi(3, "A", "C", "B"), " "))
+	memo := map[int]int64{} // fib(90) needs 62 bits: an int has 32 on 386
+	var fibm func(n int) int64
+	fibm = func(n int) int64 { // ESCAPE "func literal does not escape"
+		if n < 2 {
+			return int64(n)
+		}
+		if v, ok := memo[n]; ok {
+			return v
+		}
+		memo[n] = fibm(n-1) + fibm(n-2)
+		return memo[n]
+	}
+	fmt.Println("fibm(90):", fibm(90))
+}
@@ -0,0 +1,55 @@
+// Zero-size types and structs.HostLayout
+
+package main
+
+import (
+	"fmt"
+	"structs"
+	"unsafe"
+)
+
+// lesson: hostlayout section=zero-size-types--structshostlayout goVersion=go1.23
+func hostLayout() {
+
+	// Zero-size types: they hold no data, so they take no memory
+	type empty struct{}
+	var e empty
+	var none [0]int
+	fmt.Println("struct{}:", unsafe.Sizeof(e))
+	fmt.Println("[0]int:", unsafe.Sizeof(none))
+
+	// A zero-size field at the front or in the middle costs nothing
+	type leading struct {
+		_ struct{}
+		n int64
+	}
+	fmt.Println("leading:", unsafe.Sizeof(leading{}))
+
+	// A zero-size field at the END adds padding: otherwise &t.end would point
+	// just past the struct, into whatever object comes next in memory
+	type trailing struct {
+		n   int64
+		end struct{}
+	}
+	var t trailing
+	fmt.Println("trailing:", unsafe.Sizeof(t), "end offset:", unsafe.Offsetof(t.end))
+
+	// structs.HostLayout (Go 1.23) marks a struct whose layout must follow the
+	// host platform's C ABI, e.g. when it is shared with C or the kernel.
+	// The marker itself is zero-size, so put it first
+	type cPoint struct {
+		_ structs.HostLayout
+		x int32
+		y int32
+	}
+	fmt.Println("cPoint:", unsafe.Sizeof(cPoint{}))
+
+	// Addresses of distinct zero-size variables may or may not be equal: the
+	// spec leaves it unspecified, and the answer can change with escape
+	// analysis or the compiler version. Never rely on either outcome, e.g. to
+	// tell two zero-size values apart
+	var x, y empty
+	p, q := new(empty), new(empty)
+	fmt.Println("x, y, *p, *q:", unsafe.Sizeof(x), unsafe.Sizeof(y), unsafe.Sizeof(*p), unsafe.Sizeof(*q))
+	fmt.Println("&x == &y and p == q: may or may not be true")
+}
@@ -0,0 +1,215 @@
+// Package buildtime measures how long the go command's tools take for
+// each package. The build runs with -toolexec pointing at a wrapper that
+// times every compile, asm and link and appends a line to a log file.
+//
+// The wrapper is the calling program itself: it sets LogEnv for the
+// build, and its main function must hand over to Wrap when it finds that
+// variable set.
+package buildtime
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"basics/internal/modload"
+)
+
+// LogEnv names the environment variable holding the wrapper's log file.
+const LogEnv = "BASICS_TOOLEXEC_LOG"
+
+// Record is one run of a tool.
+type Record struct {
+	Tool     string // base name of the tool: compile, asm, link, cgo, ...
+	Package  string // import path; "main" until resolved
+	Dir      string // directory of the package's first Go file, if known
+	Duration time.Duration
+}
+
+// Wrap runs the tool command in args and appends its Record to the file
+// named by LogEnv. It returns the tool's exit code.
+func Wrap(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "buildtime: no tool to run")
+		return 2
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	start := time.Now()
+	err := cmd.Run()
+	d := time.Since(start)
+	code := 0
+	var exit *exec.ExitError
+	switch {
+	case errors.As(err, &exit):
+		code = exit.ExitCode()
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "buildtime:", err)
+		return 1
+	}
+
+	tool := strings.TrimSuffix(filepath.Base(args[0]), ".exe")
+	pkg, dir := identify(tool, args[1:])
+	if pkg == "" {
+		return code // version queries such as "compile -V=full"
+	}
+	line := fmt.Sprintf("%s\t%s\t%s\t%d\n", tool, pkg, dir, d.Nanoseconds())
+	f, err := os.OpenFile(os.Getenv(LogEnv), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "buildtime:", err)
+		return 1
+	}
+	// One small write to an O_APPEND file is not interleaved with the
+	// writes of the other wrappers running in parallel.
+	_, err = f.WriteString(line)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "buildtime:", err)
+		return 1
+	}
+	return code
+}
+
+// identify returns the import path of the package a tool run works on,
+// and the directory of its source files.
+func identify(tool string, args []string) (pkg, dir string) {
+	for i, a := range args {
+		switch {
+		case a == "-p" && i+1 < len(args):
+			pkg = args[i+1]
+		case strings.HasSuffix(a, ".go") && dir == "":
+			dir, _ = filepath.Abs(filepath.Dir(a))
+		}
+	}
+	if tool == "link" {
+		pkg = linkPackage(args)
+	}
+	return pkg, dir
+}
+
+// linkPackage finds the import path of the main package being linked:
+// the last argument is its archive, and the -importcfg file maps import
+// paths to archives. It returns "" if args are not a link of a package.
+func linkPackage(args []string) string {
+	for i, a := range args {
+		if a != "-importcfg" || i+1 >= len(args) {
+			continue
+		}
+		archive := args[len(args)-1]
+		f, err := os.Open(args[i+1])
+		if err != nil {
+			return "main"
+		}
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			path, file, ok := strings.Cut(strings.TrimPrefix(sc.Text(), "packagefile "), "=")
+			if ok && file == archive {
+				return path
+			}
+		}
+		return "main"
+	}
+	return ""
+}
+
+// Build is one timed go build.
+type Build struct {
+	Wall    time.Duration
+	Records []Record
+}
+
+// Result compares a build with an empty cache and the same build again.
+type Result struct {
+	Cold, Warm Build
+}
+
+// Measure builds the packages matching patterns in dir twice, through the
+// wrapper program exe, with a new empty GOCACHE: first cold, then warm.
+// Linked binaries are discarded.
+func Measure(ctx context.Context, dir, exe string, patterns ...string) (*Result, error) {
+	tmp, err := os.MkdirTemp("", "basics-buildtime-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	// The main packages' archives are all compiled with -p main; map
+	// their directories back to import paths.
+	pkgs, err := modload.List(ctx, dir, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	mains := make(map[string]string)
+	for _, p := range pkgs {
+		if p.Name == "main" {
+			mains[p.Dir] = p.ImportPath
+		}
+	}
+
+	toolexec := exe
+	if strings.ContainsAny(exe, " \t") {
+		toolexec = "'" + exe + "'"
+	}
+	var r Result
+	for _, b := range []*Build{&r.Cold, &r.Warm} {
+		log := filepath.Join(tmp, "toolexec.log")
+		os.Remove(log)
+		args := append([]string{"build", "-toolexec", toolexec, "-o", filepath.Join(tmp, "bin") + string(filepath.Separator)}, patterns...)
+		cmd := exec.CommandContext(ctx, "go", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOCACHE="+filepath.Join(tmp, "cache"), LogEnv+"="+log)
+		start := time.Now()
+		out, err := cmd.CombinedOutput()
+		b.Wall = time.Since(start)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("go build: %v\n%s", err, out)
+		}
+		if b.Records, err = readLog(log); err != nil {
+			return nil, err
+		}
+		for i, rec := range b.Records {
+			if rec.Package == "main" && mains[rec.Dir] != "" {
+				b.Records[i].Package = mains[rec.Dir]
+			}
+		}
+	}
+	return &r, nil
+}
+
+func readLog(file string) ([]Record, error) {
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil // every tool run was cached
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var recs []Record
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Split(sc.Text(), "\t")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("%s: bad line %q", file, sc.Text())
+		}
+		ns, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: bad line %q", file, sc.Text())
+		}
+		recs = append(recs, Record{Tool: fields[0], Package: fields[1], Dir: fields[2], Duration: time.Duration(ns)})
+	}
+	return recs, sc.Err()
+}
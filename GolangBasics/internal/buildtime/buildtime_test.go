@@ -0,0 +1,70 @@
+package buildtime
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadLog(t *testing.T) {
+	recs, err := readLog(filepath.Join("testdata", "toolexec.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Record{
+		{Tool: "compile", Package: "basics/internal/txtar", Dir: "/src/basics/internal/txtar", Duration: 41 * time.Millisecond},
+		{Tool: "compile", Package: "main", Dir: "/src/basics/cmd/basics", Duration: 912 * time.Millisecond},
+		{Tool: "asm", Package: "runtime", Dir: "/usr/local/go/src/runtime", Duration: 3500 * time.Microsecond},
+		{Tool: "link", Package: "basics/cmd/basics", Duration: 1250 * time.Millisecond},
+	}
+	if !slices.Equal(recs, want) {
+		t.Errorf("readLog:\n%v\nwant\n%v", recs, want)
+	}
+
+	// No log: every tool run was cached.
+	if recs, err := readLog(filepath.Join(t.TempDir(), "none.log")); recs != nil || err != nil {
+		t.Errorf("readLog of a missing file = %v, %v; want nil, nil", recs, err)
+	}
+
+	for _, bad := range []string{
+		"compile\tfmt\t/src/fmt\n",           // three fields
+		"compile\tfmt\t/src/fmt\t12ms\n",     // not nanoseconds
+		"compile\tfmt\t/src/fmt\t1\textra\n", // five fields
+	} {
+		file := filepath.Join(t.TempDir(), "toolexec.log")
+		if err := os.WriteFile(file, []byte(bad), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readLog(file); err == nil || !strings.Contains(err.Error(), "bad line") {
+			t.Errorf("readLog(%q) = %v, want a bad line error", bad, err)
+		}
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	importcfg := filepath.Join("testdata", "importcfg.link")
+	dir, err := filepath.Abs("src/fmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		tool     string
+		args     []string
+		pkg, dir string
+	}{
+		{"compile", []string{"-o", "/tmp/b042/_pkg_.a", "-p", "fmt", "-complete", "src/fmt/print.go", "src/fmt/scan.go"}, "fmt", dir},
+		{"compile", []string{"-V=full"}, "", ""},
+		{"asm", []string{"-p", "runtime", "-o", "/tmp/b009/asm.o", "asm_amd64.s"}, "runtime", ""},
+		{"link", []string{"-o", "/tmp/b001/exe/a.out", "-importcfg", importcfg, "-buildmode=exe", "/tmp/b001/_pkg_.a"}, "basics/cmd/basics", ""},
+		{"link", []string{"-importcfg", importcfg, "/tmp/b999/_pkg_.a"}, "main", ""}, // not in the importcfg
+		{"link", []string{"-V=full"}, "", ""},
+	} {
+		pkg, dir := identify(tt.tool, tt.args)
+		if pkg != tt.pkg || dir != tt.dir {
+			t.Errorf("identify(%s %v) = %q, %q; want %q, %q", tt.tool, tt.args, pkg, dir, tt.pkg, tt.dir)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Package deps builds the import graph of a module's packages from
+// go list -deps, for printing as a tree or as a Graphviz DOT graph.
+package deps
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"basics/internal/modload"
+)
+
+// Node is one package of the graph.
+type Node struct {
+	Path       string
+	Name       string
+	Standard   bool
+	Files      int
+	Lines      int // lines of Go source; counted for module packages only
+	Imports    []string
+	ImportedBy []string
+}
+
+// Graph is the import graph of some packages and all their dependencies.
+type Graph struct {
+	Nodes map[string]*Node
+}
+
+// Load builds the graph of the packages matching patterns in dir.
+func Load(ctx context.Context, dir string, patterns ...string) (*Graph, error) {
+	pkgs, err := modload.List(ctx, dir, append([]string{"-deps"}, patterns...)...)
+	if err != nil {
+		return nil, err
+	}
+	return newGraph(pkgs)
+}
+
+// newGraph builds the graph of packages listed by go list -deps.
+func newGraph(pkgs []*modload.Package) (*Graph, error) {
+	g := &Graph{Nodes: make(map[string]*Node)}
+	for _, p := range pkgs {
+		if p.Error != nil {
+			return nil, fmt.Errorf("%s: %s", p.ImportPath, p.Error.Err)
+		}
+		n := &Node{Path: p.ImportPath, Name: p.Name, Standard: p.Standard, Files: len(p.GoFiles)}
+		for _, imp := range p.Imports {
+			if imp != "C" { // cgo's pseudo-package
+				n.Imports = append(n.Imports, imp)
+			}
+		}
+		if !p.Standard {
+			for _, f := range p.GoFiles {
+				data, err := os.ReadFile(filepath.Join(p.Dir, f))
+				if err != nil {
+					return nil, err
+				}
+				n.Lines += bytes.Count(data, []byte("\n"))
+			}
+		}
+		g.Nodes[n.Path] = n
+	}
+	for _, n := range g.Nodes {
+		for _, imp := range n.Imports {
+			if m := g.Nodes[imp]; m != nil {
+				m.ImportedBy = append(m.ImportedBy, n.Path)
+			}
+		}
+	}
+	for _, n := range g.Nodes {
+		slices.Sort(n.ImportedBy)
+	}
+	return g, nil
+}
+
+// Local returns the module's packages, sorted by path.
+func (g *Graph) Local() []*Node {
+	var ns []*Node
+	for _, n := range g.Nodes {
+		if !n.Standard {
+			ns = append(ns, n)
+		}
+	}
+	slices.SortFunc(ns, func(a, b *Node) int { return strings.Compare(a.Path, b.Path) })
+	return ns
+}
+
+// Standard returns the standard library packages in the graph.
+func (g *Graph) Standard() []*Node {
+	var ns []*Node
+	for _, n := range g.Nodes {
+		if n.Standard {
+			ns = append(ns, n)
+		}
+	}
+	slices.SortFunc(ns, func(a, b *Node) int { return strings.Compare(a.Path, b.Path) })
+	return ns
+}
+
+// CycleRisk is a module package and the module packages it must never
+// import, because they already import it, directly or indirectly.
+type CycleRisk struct {
+	Path      string
+	Forbidden []string
+}
+
+// CycleRisks returns the cycles in waiting: for each non-main module
+// package imported by other non-main module packages, the imports that
+// would close a cycle. Go rejects import cycles, so the day such a
+// package needs something from one of these, the code has to move.
+func (g *Graph) CycleRisks() []CycleRisk {
+	var risks []CycleRisk
+	for _, n := range g.Local() {
+		if n.Name == "main" {
+			continue
+		}
+		seen := make(map[string]bool)
+		var walk func(path string)
+		walk = func(path string) {
+			for _, by := range g.Nodes[path].ImportedBy {
+				if !seen[by] && g.Nodes[by].Name != "main" {
+					seen[by] = true
+					walk(by)
+				}
+			}
+		}
+		walk(n.Path)
+		if len(seen) == 0 {
+			continue
+		}
+		r := CycleRisk{Path: n.Path}
+		for p := range seen {
+			r.Forbidden = append(r.Forbidden, p)
+		}
+		slices.Sort(r.Forbidden)
+		risks = append(risks, r)
+	}
+	return risks
+}
+
+func (n *Node) summary() string {
+	s := fmt.Sprintf("%d files", n.Files)
+	if n.Files == 1 {
+		s = "1 file"
+	}
+	if !n.Standard {
+		s += fmt.Sprintf(", %d lines", n.Lines)
+	}
+	if n.Name == "main" {
+		s = "command, " + s
+	}
+	return s
+}
+
+// WriteTree prints the module's packages as a tree rooted at the packages
+// no other module package imports. A package already printed is not
+// expanded again. Each package's standard library imports are listed on
+// one line.
+func (g *Graph) WriteTree(w io.Writer) error {
+	var b strings.Builder
+	printed := make(map[string]bool)
+	var walk func(n *Node, prefix string)
+	walk = func(n *Node, prefix string) {
+		var local, std []string
+		for _, imp := range n.Imports {
+			if m := g.Nodes[imp]; m != nil && !m.Standard {
+				local = append(local, imp)
+			} else {
+				std = append(std, imp)
+			}
+		}
+		if len(std) > 0 {
+			bar := "│"
+			if len(local) == 0 {
+				bar = " "
+			}
+			fmt.Fprintf(&b, "%s%s   std: %s\n", prefix, bar, strings.Join(std, " "))
+		}
+		for i, imp := range local {
+			m := g.Nodes[imp]
+			branch, indent := "├── ", "│   "
+			if i == len(local)-1 {
+				branch, indent = "└── ", "    "
+			}
+			if printed[imp] {
+				fmt.Fprintf(&b, "%s%s%s (see above)\n", prefix, branch, imp)
+				continue
+			}
+			printed[imp] = true
+			fmt.Fprintf(&b, "%s%s%s (%s)\n", prefix, branch, imp, m.summary())
+			walk(m, prefix+indent)
+		}
+	}
+	for _, n := range g.Local() {
+		if len(n.ImportedBy) > 0 {
+			continue
+		}
+		printed[n.Path] = true
+		fmt.Fprintf(&b, "%s (%s)\n", n.Path, n.summary())
+		walk(n, "")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteDOT prints the graph in Graphviz DOT format. Standard library
+// packages appear only where a module package imports them, unless std
+// is set, in which case their own imports are drawn too.
+func (g *Graph) WriteDOT(w io.Writer, std bool) error {
+	var b strings.Builder
+	b.WriteString("digraph deps {\n\trankdir=LR;\n\tnode [fontname=\"Helvetica\"];\n")
+	drawn := make(map[string]bool)
+	node := func(n *Node) {
+		if drawn[n.Path] {
+			return
+		}
+		drawn[n.Path] = true
+		if n.Standard {
+			fmt.Fprintf(&b, "\t%q [shape=ellipse, color=gray50, fontcolor=gray50];\n", n.Path)
+			return
+		}
+		fmt.Fprintf(&b, "\t%q [shape=box, label=%q];\n", n.Path, n.Path+"\n"+n.summary())
+	}
+	var from []*Node
+	from = append(from, g.Local()...)
+	if std {
+		from = append(from, g.Standard()...)
+	}
+	for _, n := range from {
+		node(n)
+		for _, imp := range n.Imports {
+			m := g.Nodes[imp]
+			if m == nil {
+				continue
+			}
+			node(m)
+			attr := ""
+			if m.Standard {
+				attr = " [color=gray70]"
+			}
+			fmt.Fprintf(&b, "\t%q -> %q%s;\n", n.Path, imp, attr)
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
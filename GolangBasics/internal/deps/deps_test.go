@@ -0,0 +1,98 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"basics/internal/modload"
+)
+
+// fixture returns the graph of testdata/golist.json, the output of
+// go list -e -json -deps for the module in testdata/m.
+func fixture(t *testing.T) *Graph {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", "golist.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	pkgs, err := modload.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := newGraph(pkgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestNewGraph(t *testing.T) {
+	g := fixture(t)
+	if len(g.Nodes) != 6 {
+		t.Fatalf("%d nodes, want 6", len(g.Nodes))
+	}
+	for _, want := range []Node{
+		{Path: "m", Name: "main", Files: 1, Lines: 10, Imports: []string{"fmt", "m/a", "m/b"}},
+		{Path: "m/a", Name: "a", Files: 1, Lines: 10, Imports: []string{"m/b", "strings"}, ImportedBy: []string{"m"}}, // without "C"
+		{Path: "m/b", Name: "b", Files: 2, Lines: 8, Imports: []string{"errors"}, ImportedBy: []string{"m", "m/a"}},
+		{Path: "errors", Name: "errors", Standard: true, Files: 3, Imports: []string{"internal/reflectlite"}, ImportedBy: []string{"fmt", "m/b"}},
+	} {
+		n := g.Nodes[want.Path]
+		if n == nil {
+			t.Errorf("no node %s", want.Path)
+			continue
+		}
+		if n.Name != want.Name || n.Standard != want.Standard || n.Files != want.Files || n.Lines != want.Lines ||
+			!slices.Equal(n.Imports, want.Imports) || !slices.Equal(n.ImportedBy, want.ImportedBy) {
+			t.Errorf("node %s:\n%+v\nwant\n%+v", want.Path, *n, want)
+		}
+	}
+
+	var local, std []string
+	for _, n := range g.Local() {
+		local = append(local, n.Path)
+	}
+	for _, n := range g.Standard() {
+		std = append(std, n.Path)
+	}
+	if !slices.Equal(local, []string{"m", "m/a", "m/b"}) || !slices.Equal(std, []string{"errors", "fmt", "strings"}) {
+		t.Errorf("Local %v, Standard %v", local, std)
+	}
+
+	risks := g.CycleRisks()
+	if len(risks) != 1 || risks[0].Path != "m/b" || !slices.Equal(risks[0].Forbidden, []string{"m/a"}) {
+		t.Errorf("CycleRisks = %+v, want m/b must not import m/a", risks)
+	}
+}
+
+func TestNewGraphError(t *testing.T) {
+	pkgs, err := modload.Decode(strings.NewReader(`{"ImportPath": "m/bad", "Error": {"Err": "no Go files in /m/bad"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newGraph(pkgs); err == nil || err.Error() != "m/bad: no Go files in /m/bad" {
+		t.Errorf("newGraph of a broken package: %v", err)
+	}
+}
+
+func TestWriteTree(t *testing.T) {
+	var b strings.Builder
+	if err := fixture(t).WriteTree(&b); err != nil {
+		t.Fatal(err)
+	}
+	const want = `m (command, 1 file, 10 lines)
+│   std: fmt
+├── m/a (1 file, 10 lines)
+│   │   std: strings
+│   └── m/b (2 files, 8 lines)
+│           std: errors
+└── m/b (see above)
+`
+	if b.String() != want {
+		t.Errorf("WriteTree:\n%s\nwant\n%s", b.String(), want)
+	}
+}
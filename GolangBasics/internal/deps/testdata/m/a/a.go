@@ -0,0 +1,10 @@
+package a
+
+import (
+	"C"
+	"strings"
+
+	"m/b"
+)
+
+func A() string { return strings.ToUpper(b.B()) }
@@ -0,0 +1,5 @@
+package b
+
+import "errors"
+
+var ErrB = errors.New("b")
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"fmt"
+
+	"m/a"
+	"m/b"
+)
+
+func main() { fmt.Println(a.A(), b.B()) }
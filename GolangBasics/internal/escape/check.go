@@ -0,0 +1,227 @@
+package escape
+
+import (
+	"cmp"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Kind is the kind of decision a diagnostic reports.
+type Kind int
+
+const (
+	Escape Kind = iota // "moved to heap: x", "x escapes to heap", "x does not escape", "leaking param: x"
+	Inline             // "can inline f", "inlining call to f"
+)
+
+func (k Kind) String() string {
+	if k == Inline {
+		return "INLINE"
+	}
+	return "ESCAPE"
+}
+
+// KindOf classifies a diagnostic message. Messages of neither kind, such
+// as "devirtualizing", are never checked.
+func KindOf(msg string) (Kind, bool) {
+	switch {
+	case strings.HasPrefix(msg, "can inline "), strings.HasPrefix(msg, "inlining call to "):
+		return Inline, true
+	case strings.HasPrefix(msg, "moved to heap: "), strings.HasPrefix(msg, "leaking param"),
+		strings.HasSuffix(msg, " escapes to heap"), strings.HasSuffix(msg, " does not escape"):
+		return Escape, true
+	}
+	return 0, false
+}
+
+// An Expectation is a comment claiming what the compiler reports for the
+// line it is on, in the style of the errorcheck tests in the Go
+// repository:
+//
+//	x := 42  // ESCAPE "moved to heap: x"
+//	f(x)     // INLINE "inlining call to f"
+//	g := func() { ... }  // INLINE ESCAPE "func literal does not escape"
+//
+// Each quoted string is a regular expression (Go syntax, "..." or `...`)
+// that must match a diagnostic of that kind on the line. A keyword with no
+// patterns only requires some diagnostic of its kind.
+type Expectation struct {
+	File     string // slash-separated path relative to the module root, like Diagnostic.File
+	Line     int
+	Kind     Kind
+	Patterns []*regexp.Regexp
+}
+
+// ParseExpectations returns the expectations in the comments of the Go
+// source src. name is the file's path relative to the module root. A comment is an expectation
+// if its text, or the text after a later "//" in it, starts with ESCAPE or
+// INLINE.
+func ParseExpectations(name string, src []byte) ([]Expectation, error) {
+	fset := token.NewFileSet()
+	file := fset.AddFile(name, -1, len(src))
+	var errs scanner.ErrorList
+	var s scanner.Scanner
+	s.Init(file, src, func(pos token.Position, msg string) { errs.Add(pos, msg) }, scanner.ScanComments)
+	var exps []Expectation
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok != token.COMMENT || !strings.HasPrefix(lit, "//") {
+			continue
+		}
+		// The expectation may follow an ordinary comment:
+		// "// explanation // ESCAPE ...".
+		p := fset.Position(pos)
+		var es []Expectation
+		var err error
+		for text := lit; es == nil && err == nil; {
+			i := strings.Index(text, "//")
+			if i < 0 {
+				break
+			}
+			text = text[i+2:]
+			es, err = parseComment(text)
+		}
+		if err != nil {
+			errs.Add(p, err.Error())
+			continue
+		}
+		for _, e := range es {
+			e.File, e.Line = name, p.Line
+			exps = append(exps, e)
+		}
+	}
+	return exps, errs.Err()
+}
+
+// parseComment parses the text after a "//": a keyword, its patterns,
+// optionally another keyword and its patterns. It returns nil if the text
+// does not start with a keyword.
+func parseComment(text string) ([]Expectation, error) {
+	var exps []Expectation
+	for text = strings.TrimSpace(text); text != ""; text = strings.TrimSpace(text) {
+		if text[0] == '"' || text[0] == '`' {
+			if len(exps) == 0 {
+				return nil, nil
+			}
+			q, err := strconv.QuotedPrefix(text)
+			if err != nil {
+				return nil, fmt.Errorf("bad pattern %s", text)
+			}
+			text = text[len(q):]
+			pat, _ := strconv.Unquote(q)
+			re, err := regexp.Compile(pat)
+			if err != nil {
+				return nil, err
+			}
+			e := &exps[len(exps)-1]
+			e.Patterns = append(e.Patterns, re)
+			continue
+		}
+		word := text
+		if i := strings.IndexAny(text, " \t\"`"); i >= 0 {
+			word = text[:i]
+		}
+		text = text[len(word):]
+		switch word {
+		case "ESCAPE":
+			exps = append(exps, Expectation{Kind: Escape})
+		case "INLINE":
+			exps = append(exps, Expectation{Kind: Inline})
+		default:
+			if len(exps) == 0 {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("%s: want ESCAPE, INLINE or a quoted pattern", word)
+		}
+	}
+	return exps, nil
+}
+
+// A Problem is an expectation the compiler did not meet, or a diagnostic
+// no expectation accounts for.
+type Problem struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Msg)
+}
+
+// Check matches diagnostics against expectations, as the compiler's
+// errorcheck tests do, and returns the problems ordered by position:
+//
+//   - a pattern matching no diagnostic of its kind on its line is missing;
+//   - on a line with an expectation of some kind, every diagnostic of that
+//     kind must be matched by one of its patterns;
+//   - in a file with any expectation, "moved to heap" is unexpected on a
+//     line without an ESCAPE expectation, so a claim that a variable stays
+//     on the stack needs no comment to be checked.
+//
+// Diagnostics on other lines are ignored: -m reports every argument of
+// every fmt.Println, and a file need only claim what it explains.
+func Check(exps []Expectation, diags []Diagnostic) []Problem {
+	type key struct {
+		file string
+		line int
+		kind Kind
+	}
+	byLine := make(map[key][]int)
+	for i, d := range diags {
+		if k, ok := KindOf(d.Msg); ok {
+			byLine[key{d.File, d.Line, k}] = append(byLine[key{d.File, d.Line, k}], i)
+		}
+	}
+	var problems []Problem
+	matched := make([]bool, len(diags))
+	expected := make(map[key]bool)
+	files := make(map[string]bool)
+	for _, e := range exps {
+		k := key{e.File, e.Line, e.Kind}
+		expected[k] = true
+		files[e.File] = true
+		got := byLine[k]
+		if len(e.Patterns) == 0 {
+			if len(got) == 0 {
+				problems = append(problems, Problem{File: e.File, Line: e.Line, Msg: fmt.Sprintf("missing %s diagnostic", e.Kind)})
+			}
+			for _, i := range got {
+				matched[i] = true
+			}
+			continue
+		}
+		for _, re := range e.Patterns {
+			found := false
+			for _, i := range got {
+				if re.MatchString(diags[i].Msg) {
+					matched[i], found = true, true
+				}
+			}
+			if !found {
+				problems = append(problems, Problem{File: e.File, Line: e.Line, Msg: fmt.Sprintf("missing %s %q", e.Kind, re)})
+			}
+		}
+	}
+	for i, d := range diags {
+		k, ok := KindOf(d.Msg)
+		if !ok || matched[i] {
+			continue
+		}
+		if expected[key{d.File, d.Line, k}] || files[d.File] && strings.HasPrefix(d.Msg, "moved to heap: ") {
+			problems = append(problems, Problem{File: d.File, Line: d.Line, Msg: "unexpected " + k.String() + ": " + d.Msg})
+		}
+	}
+	slices.SortStableFunc(problems, func(a, b Problem) int {
+		return cmp.Or(strings.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line))
+	})
+	return problems
+}
@@ -0,0 +1,128 @@
+package escape
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestModule checks the expectations of the lessons and README examples
+// against the compiler, like "basics errorcheck".
+func TestModule(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the compiler")
+	}
+	m, err := LoadModule(context.Background(), "../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Expectations) == 0 {
+		t.Fatal("no expectations found")
+	}
+	for _, p := range Check(m.Expectations, m.Diagnostics) {
+		t.Error(p)
+	}
+}
+
+func TestParseExpectations(t *testing.T) {
+	const src = "package p\n" +
+		"\n" +
+		"func f() {\n" +
+		"	x := 42 // ESCAPE \"moved to heap: x\"\n" +
+		"	g := func() {} // INLINE ESCAPE `func literal does not escape` \"literal\"\n" +
+		"	h(x) // calls h, which keeps x // ESCAPE\n" +
+		"	// an ESCAPE in the middle of a comment is not an expectation\n" +
+		"	y := 1 // INLINE \"can inline\" ESCAPE\n" +
+		"}\n"
+	exps, err := ParseExpectations("dir/p.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type exp struct {
+		line     int
+		kind     Kind
+		patterns string
+	}
+	want := []exp{
+		{4, Escape, "moved to heap: x"},
+		{5, Inline, ""},
+		{5, Escape, "func literal does not escape|literal"},
+		{6, Escape, ""}, // after a plain comment
+		{8, Inline, "can inline"},
+		{8, Escape, ""},
+	}
+	var got []exp
+	for _, e := range exps {
+		if e.File != "dir/p.go" {
+			t.Errorf("expectation in %s, want dir/p.go", e.File)
+		}
+		var pats []string
+		for _, re := range e.Patterns {
+			pats = append(pats, re.String())
+		}
+		got = append(got, exp{e.Line, e.Kind, strings.Join(pats, "|")})
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("ParseExpectations:\n%v\nwant\n%v", got, want)
+	}
+
+	for _, bad := range []string{
+		`x := 1 // ESCAPE "moved to (heap"`, // not a regexp
+		`x := 1 // ESCAPE "unterminated`,
+		`x := 1 // ESCAPE moved`,
+	} {
+		if _, err := ParseExpectations("p.go", []byte("package p\n\nfunc f() {\n\t"+bad+"\n}\n")); err == nil || !strings.HasPrefix(err.Error(), "p.go:4:") {
+			t.Errorf("%s: error %v, want one at p.go:4", bad, err)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	exps, err := ParseExpectations("p/p.go", []byte("package p\n"+
+		"// ESCAPE \"moved to heap: x\"\n"+ // line 2: met
+		"// ESCAPE \"moved to heap: y\"\n"+ // line 3: missing
+		"// INLINE\n"+ // line 4: needs any inlining diagnostic
+		"// ESCAPE \"leaking param: a\"\n"+ // line 5: another escape diagnostic is unexpected
+		"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := []Diagnostic{
+		{File: "p/p.go", Line: 2, Msg: "moved to heap: x"},
+		{File: "p/p.go", Line: 3, Msg: "can inline f"}, // another kind: not checked
+		{File: "p/p.go", Line: 5, Msg: "leaking param: a"},
+		{File: "p/p.go", Line: 5, Msg: "b does not escape"},
+		{File: "p/p.go", Line: 6, Msg: "moved to heap: z"},       // no expectation on the line
+		{File: "p/p.go", Line: 7, Msg: "s does not escape"},      // only moves to the heap are checked
+		{File: "q/p.go", Line: 6, Msg: "moved to heap: z"},       // a file without expectations
+		{File: "p/p.go", Line: 8, Msg: "devirtualizing f to *T"}, // of neither kind
+	}
+	var got []string
+	for _, p := range Check(exps, diags) {
+		got = append(got, p.String())
+	}
+	want := []string{
+		`p/p.go:3: missing ESCAPE "moved to heap: y"`,
+		"p/p.go:4: missing INLINE diagnostic",
+		"p/p.go:5: unexpected ESCAPE: b does not escape",
+		"p/p.go:6: unexpected ESCAPE: moved to heap: z",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Check:\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParse(t *testing.T) {
+	out := []byte("# basics\n" +
+		"./pointers.go:12:3: moved to heap: x\n" +
+		"b12/readme12.go:5:2: can inline f\n" +
+		"not a diagnostic\n")
+	want := []Diagnostic{
+		{File: "pointers.go", Line: 12, Col: 3, Msg: "moved to heap: x"},
+		{File: "b12/readme12.go", Line: 5, Col: 2, Msg: "can inline f"},
+	}
+	if got := Parse(out); !slices.Equal(got, want) {
+		t.Errorf("Parse:\n%v\nwant\n%v", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+// Package escape runs the compiler's optimization diagnostics
+// (go build -gcflags=-m) and parses them, so tools can ask where the
+// compiler put a variable instead of guessing.
+package escape
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// Diagnostic is one line of -m output, e.g.
+//
+//	./pointers.go:12:3: moved to heap: x
+type Diagnostic struct {
+	File string // slash-separated path relative to the directory built in, e.g. "pointers.go"
+	Line int
+	Col  int
+	Msg  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Col, d.Msg)
+}
+
+var diagRE = regexp.MustCompile(`^(.+\.go):(\d+):(\d+): (.*)$`)
+
+// Parse extracts the diagnostics from compiler output. Other lines, such
+// as "# package" headers, are ignored. The go command prints the files
+// of packages below the directory it runs in relative to it, so files
+// in different packages are told apart.
+func Parse(out []byte) []Diagnostic {
+	var diags []Diagnostic
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		m := diagRE.FindStringSubmatch(sc.Text())
+		if m == nil {
+			continue
+		}
+		line, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		diags = append(diags, Diagnostic{File: filepath.ToSlash(filepath.Clean(m[1])), Line: line, Col: col, Msg: m[4]})
+	}
+	return diags
+}
+
+// Build compiles the packages in dir matching pkgs (default ".") with
+// -gcflags set to gcflags (default "-m") and returns the diagnostics. The
+// binaries are discarded.
+func Build(ctx context.Context, dir, gcflags string, pkgs ...string) ([]Diagnostic, error) {
+	if gcflags == "" {
+		gcflags = "-m"
+	}
+	if len(pkgs) == 0 {
+		pkgs = []string{"."}
+	}
+	cmd := exec.CommandContext(ctx, "go", append([]string{"build", "-gcflags=" + gcflags, "-o", os.DevNull}, pkgs...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("go build -gcflags=%s: %v\n%s", gcflags, err, out)
+	}
+	return Parse(out), nil
+}
@@ -0,0 +1,159 @@
+package escape
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"basics/internal/lesson"
+)
+
+// Module is what the compiler is expected to report for a lesson module,
+// and what it does report.
+type Module struct {
+	Files        []string // files with expectations: the package's, then README.md
+	Expectations []Expectation
+	Diagnostics  []Diagnostic
+}
+
+// LoadModule parses the expectations of the root package in dir, and of
+// the Go code blocks of its README.md, and compiles both with -m. Only the
+// files this host compiles are read, since the compiler reports nothing for
+// the others; each README block is compiled as a package of its own so
+// that blocks may declare the same names.
+func LoadModule(ctx context.Context, dir string) (*Module, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	m := new(Module)
+	for _, name := range bp.GoFiles {
+		src, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		es, err := ParseExpectations(name, src)
+		if err != nil {
+			return nil, err
+		}
+		if len(es) > 0 {
+			m.Expectations = append(m.Expectations, es...)
+			m.Files = append(m.Files, name)
+		}
+	}
+	m.Diagnostics, err = Build(ctx, dir, "-m")
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := readmeBlocks(filepath.Join(dir, "README.md"))
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) > 0 {
+		diags, exps, err := checkBlocks(ctx, dir, blocks)
+		if err != nil {
+			return nil, err
+		}
+		m.Diagnostics = append(m.Diagnostics, diags...)
+		m.Expectations = append(m.Expectations, exps...)
+		m.Files = append(m.Files, "README.md")
+	}
+	return m, nil
+}
+
+// A block is a ```go code block of the README with expectations in it.
+type block struct {
+	line int // line of the opening fence
+	src  string
+}
+
+// readmeBlocks returns the Go code blocks of the Markdown file that contain
+// ESCAPE or INLINE comments.
+func readmeBlocks(file string) ([]block, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var blocks []block
+	var cur *block
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		switch {
+		case cur == nil && strings.TrimSpace(text) == "```go":
+			cur = &block{line: line}
+		case cur != nil && strings.TrimSpace(text) == "```":
+			if es, err := ParseExpectations("README.md", []byte(cur.src)); err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", file, cur.line, err)
+			} else if len(es) > 0 {
+				blocks = append(blocks, *cur)
+			}
+			cur = nil
+		case cur != nil:
+			cur.src += text + "\n"
+		}
+	}
+	return blocks, sc.Err()
+}
+
+// checkBlocks compiles each block as a package in a temporary module and
+// returns the diagnostics and expectations with their positions mapped
+// back to README.md.
+func checkBlocks(ctx context.Context, dir string, blocks []block) ([]Diagnostic, []Expectation, error) {
+	modGo, err := lesson.ModuleGoVersion(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmp, err := os.MkdirTemp("", "basics-errorcheck-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(tmp)
+	if err := os.WriteFile(filepath.Join(tmp, "go.mod"), []byte("module readme\n\ngo "+strings.TrimPrefix(modGo, "go")+"\n"), 0o644); err != nil {
+		return nil, nil, err
+	}
+
+	// The file for the block at README line n is b<n>/readme<n>.go; its
+	// line k is README line n+k-2, after "package p" and a blank line.
+	const header = "package p\n\n"
+	offset := make(map[string]int)
+	var exps []Expectation
+	for _, b := range blocks {
+		name := fmt.Sprintf("b%d/readme%d.go", b.line, b.line)
+		offset[name] = b.line - 2
+		path := filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.Mkdir(filepath.Dir(path), 0o755); err != nil {
+			return nil, nil, err
+		}
+		if err := os.WriteFile(path, []byte(header+b.src), 0o644); err != nil {
+			return nil, nil, err
+		}
+		es, err := ParseExpectations(name, []byte(header+b.src))
+		if err != nil {
+			return nil, nil, err
+		}
+		exps = append(exps, es...)
+	}
+	diags, err := Build(ctx, tmp, "-m", "./...")
+	if err != nil {
+		return nil, nil, fmt.Errorf("README.md examples (b<line>/readme<line>.go is the block at that line): %v", err)
+	}
+	var kept []Diagnostic
+	for _, d := range diags {
+		if off, ok := offset[d.File]; ok {
+			d.File, d.Line = "README.md", d.Line+off
+			kept = append(kept, d)
+		}
+	}
+	for i := range exps {
+		exps[i].Line += offset[exps[i].File]
+		exps[i].File = "README.md"
+	}
+	return kept, exps, nil
+}
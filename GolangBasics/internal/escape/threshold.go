@@ -0,0 +1,135 @@
+package escape
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A Construct is an allocation whose placement depends on its size.
+type Construct struct {
+	Name string // how the lesson writes it
+	Stmt string // Go statement declaring buf, with %d for the size
+	Heap string // substring of the -m message reporting a heap allocation
+}
+
+// Constructs are the allocations the threshold search explores: a local
+// array variable, and the implicit allocations of make and new.
+var Constructs = []Construct{
+	{Name: "var buf [N]byte", Stmt: "var buf [%d]byte", Heap: "moved to heap: buf"},
+	{Name: "buf := make([]byte, N)", Stmt: "buf := make([]byte, %d)", Heap: "escapes to heap"},
+	{Name: "buf := new([N]byte)", Stmt: "buf := new([%d]byte)", Heap: "escapes to heap"},
+}
+
+// Threshold is the result of the search for one construct.
+type Threshold struct {
+	Construct Construct
+	Stack     int64 // largest size that stays on the stack
+	Heap      int64 // smallest size that moves to the heap; 0 if none up to the limit
+	Probes    int
+}
+
+// FindThresholds binary-searches, for each construct, the size in bytes at
+// which the installed compiler moves it from the stack to the heap,
+// searching sizes up to limit. All constructs are probed in one generated
+// program per step, so a search costs about log2(limit) builds.
+func FindThresholds(ctx context.Context, limit int64) ([]Threshold, error) {
+	dir, err := os.MkdirTemp("", "basics-threshold-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module threshold\n"), 0o644); err != nil {
+		return nil, err
+	}
+
+	ts := make([]Threshold, len(Constructs))
+	lo := make([]int64, len(Constructs)) // known to stay on the stack
+	hi := make([]int64, len(Constructs)) // known to move to the heap, or unknown
+	for i, c := range Constructs {
+		ts[i].Construct = c
+		lo[i], hi[i] = 0, limit+1
+	}
+	first := true
+	for {
+		sizes := make([]int64, len(Constructs))
+		done := true
+		for i := range Constructs {
+			switch {
+			case first:
+				sizes[i] = limit
+				done = false
+			case hi[i]-lo[i] > 1:
+				sizes[i] = lo[i] + (hi[i]-lo[i])/2
+				done = false
+			default:
+				sizes[i] = max(lo[i], 1)
+			}
+		}
+		if done {
+			break
+		}
+		heap, err := probe(ctx, dir, sizes)
+		if err != nil {
+			return nil, err
+		}
+		for i := range Constructs {
+			if !first && hi[i]-lo[i] <= 1 {
+				continue
+			}
+			ts[i].Probes++
+			if heap[i] {
+				hi[i] = sizes[i]
+			} else {
+				lo[i] = sizes[i]
+			}
+		}
+		first = false
+	}
+	for i := range ts {
+		ts[i].Stack = lo[i]
+		if hi[i] <= limit {
+			ts[i].Heap = hi[i]
+		}
+	}
+	return ts, nil
+}
+
+// probe builds a program containing each construct at the given size and
+// reports which ones the compiler put on the heap.
+func probe(ctx context.Context, dir string, sizes []int64) ([]bool, error) {
+	var src strings.Builder
+	src.WriteString("package main\n\n")
+	lines := make([]int, len(Constructs))
+	line := 3
+	for i, c := range Constructs {
+		fmt.Fprintf(&src, "//go:noinline\nfunc f%d(i int) byte {\n\t", i)
+		lines[i] = line + 2
+		fmt.Fprintf(&src, c.Stmt, sizes[i])
+		src.WriteString("\n\tbuf[i] = 1\n\treturn buf[0]\n}\n\n")
+		line += 7
+	}
+	src.WriteString("func main() {\n")
+	for i := range Constructs {
+		fmt.Fprintf(&src, "\tprintln(f%d(0))\n", i)
+	}
+	src.WriteString("}\n")
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(src.String()), 0o644); err != nil {
+		return nil, err
+	}
+	diags, err := Build(ctx, dir, "-m")
+	if err != nil {
+		return nil, err
+	}
+	heap := make([]bool, len(Constructs))
+	for _, d := range diags {
+		for i, c := range Constructs {
+			if d.Line == lines[i] && strings.Contains(d.Msg, c.Heap) {
+				heap[i] = true
+			}
+		}
+	}
+	return heap, nil
+}
@@ -0,0 +1,51 @@
+package escape
+
+import (
+	"context"
+	"errors"
+	"math/bits"
+	"testing"
+)
+
+// TestFindThresholds checks the search against the limits of the gc
+// compiler: 128 KiB for a variable, 64 KiB for the implicit allocations of
+// make and new (ir.MaxStackVarSize and ir.MaxImplicitStackVarSize).
+func TestFindThresholds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the compiler")
+	}
+	for _, tt := range []struct {
+		limit int64
+		stack []int64
+		heap  []int64
+	}{
+		{limit: 256 << 10, stack: []int64{128 << 10, 64 << 10, 64 << 10}, heap: []int64{128<<10 + 1, 64<<10 + 1, 64<<10 + 1}},
+		// below every threshold, each construct stays on the stack up to the limit
+		{limit: 1000, stack: []int64{1000, 1000, 1000}, heap: []int64{0, 0, 0}},
+	} {
+		ts, err := FindThresholds(context.Background(), tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ts) != len(Constructs) {
+			t.Fatalf("limit %d: %d thresholds, want %d", tt.limit, len(ts), len(Constructs))
+		}
+		for i, th := range ts {
+			if th.Stack != tt.stack[i] || th.Heap != tt.heap[i] {
+				t.Errorf("limit %d: %s: stack up to %d, heap from %d; want %d and %d", tt.limit, th.Construct.Name, th.Stack, th.Heap, tt.stack[i], tt.heap[i])
+			}
+			// one probe at the limit, then a binary search of [0, limit]
+			if most := 1 + bits.Len64(uint64(tt.limit)); th.Probes > most {
+				t.Errorf("limit %d: %s: %d probes, want at most %d", tt.limit, th.Construct.Name, th.Probes, most)
+			}
+		}
+	}
+}
+
+func TestFindThresholdsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := FindThresholds(ctx, 1<<20); !errors.Is(err, context.Canceled) {
+		t.Errorf("FindThresholds with a canceled context = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,126 @@
+package interp
+
+import (
+	"fmt"
+	"go/types"
+	"io"
+	"strings"
+)
+
+// WriteDiagram renders the trace as a memory-flow diagram in the style
+// of the README:
+//
+//	Stack of main():
+//	├─ num = 5 @ address 0x1000
+//
+//	Function call increment(x int):
+//	├─ x = 5 (copy) @ address 0x2000  [NEW STACK FRAME]
+//	└─ x++ → x = 6
+//
+//	Return to main():
+//	└─ num still = 5 @ address 0x1000
+func (t *Trace) WriteDiagram(w io.Writer) error {
+	d := &diagram{w: w}
+	for _, e := range t.Events {
+		switch e.Kind {
+		case EvCall:
+			d.flush()
+			if e.Frame.Depth == 0 {
+				d.section("Stack of " + e.Frame.Func + "():")
+			} else {
+				d.section("Function call " + e.Frame.Sig + ":")
+				d.fresh = true
+			}
+		case EvReturn:
+			d.flush()
+			d.section("Return to " + e.Frame.Func + "():")
+			if len(e.Ret) > 0 {
+				d.line("returned " + strings.Join(e.Ret, ", "))
+			}
+			for _, w := range e.Watch {
+				if w.Before == w.After {
+					d.line(fmt.Sprintf("%s still = %s @ address %s", w.Var.Name, w.After, hex(w.Var.Addr)))
+				} else {
+					d.line(fmt.Sprintf("%s now = %s @ address %s (modified via pointer)", w.Var.Name, w.After, hex(w.Var.Addr)))
+				}
+			}
+		case EvAlloc:
+			if e.Frame == nil && e.Var.Frame == nil && !e.Var.Heap {
+				d.line(fmt.Sprintf("%s = %s @ address %s [DATA]", e.Var.Name, e.Value, hex(e.Var.Addr)))
+				continue
+			}
+			s := e.Var.Name + " = " + e.Value
+			if e.Copy {
+				if _, ok := e.Var.Type.Underlying().(*types.Pointer); ok {
+					s += " (address copy)"
+				} else {
+					s += " (copy)"
+				}
+			}
+			s += " @ address " + hex(e.Var.Addr)
+			if e.Var.Heap {
+				s += " [HEAP: " + e.Var.Reason + "]"
+			}
+			if d.fresh {
+				s += "  [NEW STACK FRAME]"
+				d.fresh = false
+			}
+			d.line(s)
+		case EvStore:
+			name := e.Var.Name + e.Path
+			if e.Via {
+				d.line(fmt.Sprintf("%s dereferences to address %s → %s = %s", e.Text, hex(e.Var.Addr), name, e.Value))
+			} else {
+				d.line(fmt.Sprintf("%s → %s = %s", e.Text, name, e.Value))
+			}
+		case EvOutput:
+			d.line("output: " + e.Text)
+		}
+	}
+	d.flush()
+	return d.err
+}
+
+type diagram struct {
+	w       io.Writer
+	err     error
+	started bool
+	header  string
+	lines   []string
+	fresh   bool // next allocation is the first in a new frame
+}
+
+func (d *diagram) section(header string) {
+	d.header = header
+}
+
+func (d *diagram) line(s string) {
+	d.lines = append(d.lines, s)
+}
+
+func (d *diagram) flush() {
+	if d.header == "" && len(d.lines) == 0 {
+		return
+	}
+	var b strings.Builder
+	if d.started {
+		b.WriteString("\n")
+	}
+	if d.header != "" {
+		b.WriteString(d.header + "\n")
+	}
+	for i, l := range d.lines {
+		if i == len(d.lines)-1 {
+			b.WriteString("└─ " + l + "\n")
+		} else {
+			b.WriteString("├─ " + l + "\n")
+		}
+	}
+	if d.err == nil {
+		_, d.err = io.WriteString(d.w, b.String())
+	}
+	d.started = true
+	d.header = ""
+	d.lines = nil
+	d.fresh = false
+}
@@ -0,0 +1,657 @@
+package interp
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// tuple is the result of a call with several results.
+type tuple []value
+
+// lvalue is an addressable location: a variable, or a field of one.
+type lvalue struct {
+	v    *Var
+	path []int
+	via  bool // reached through a pointer
+}
+
+func (in *interp) lvalue(e ast.Expr) (*lvalue, error) {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return in.lvalue(e.X)
+	case *ast.Ident:
+		obj, ok := in.Info.Uses[e].(*types.Var)
+		if !ok {
+			if obj, ok = in.Info.Defs[e].(*types.Var); !ok {
+				return nil, in.errorf(e, "interp: %s is not a variable", e.Name)
+			}
+		}
+		v, err := in.variable(e, obj)
+		if err != nil {
+			return nil, err
+		}
+		return &lvalue{v: v}, nil
+	case *ast.StarExpr:
+		p, err := in.eval(e.X)
+		if err != nil {
+			return nil, err
+		}
+		return in.deref(e, p.(pointer))
+	case *ast.SelectorExpr:
+		sel := in.Info.Selections[e]
+		if sel == nil || sel.Kind() != types.FieldVal {
+			return nil, in.unsupported(e)
+		}
+		var base *lvalue
+		if _, ok := in.Info.TypeOf(e.X).Underlying().(*types.Pointer); ok {
+			p, err := in.eval(e.X)
+			if err != nil {
+				return nil, err
+			}
+			if base, err = in.deref(e, p.(pointer)); err != nil {
+				return nil, err
+			}
+		} else {
+			var err error
+			if base, err = in.lvalue(e.X); err != nil {
+				return nil, err
+			}
+		}
+		lv := &lvalue{v: base.v, path: append(append([]int(nil), base.path...), sel.Index()...), via: base.via}
+		return lv, nil
+	}
+	return nil, in.unsupported(e)
+}
+
+func (in *interp) deref(n ast.Node, p pointer) (*lvalue, error) {
+	if p.isNil() {
+		return nil, in.errorf(n, "%v", errNilDeref)
+	}
+	return &lvalue{v: p.v, path: p.path, via: true}, nil
+}
+
+// load returns a copy of the value p points to.
+func (in *interp) load(p pointer) value {
+	return in.lload(&lvalue{v: p.v, path: p.path})
+}
+
+func (in *interp) lload(lv *lvalue) value {
+	v := lv.v.val
+	for _, i := range lv.path {
+		v = v.(structVal)[i]
+	}
+	return copyVal(v)
+}
+
+// store writes v to lv and records the write, attributed to statement s.
+func (in *interp) store(lv *lvalue, v value, s ast.Stmt) {
+	t := lv.v.Type
+	var path strings.Builder
+	for _, i := range lv.path {
+		f := t.Underlying().(*types.Struct).Field(i)
+		path.WriteString("." + f.Name())
+		t = f.Type()
+	}
+	v = in.assignable(v, t)
+	if len(lv.path) == 0 {
+		lv.v.val = v
+	} else {
+		s := lv.v.val.(structVal)
+		for _, i := range lv.path[:len(lv.path)-1] {
+			s = s[i].(structVal)
+		}
+		s[lv.path[len(lv.path)-1]] = v
+	}
+	f := in.mem.top()
+	via := lv.via || (lv.v.Frame != nil && lv.v.Frame != f)
+	in.mem.emit(Event{Kind: EvStore, Frame: f, Var: lv.v, Path: path.String(), Value: in.describe(v, t), Text: in.text(s), Via: via})
+}
+
+func (in *interp) eval(e ast.Expr) (value, error) {
+	tv := in.Info.Types[e]
+	if tv.Value != nil {
+		v, err := fromConst(tv.Value, tv.Type)
+		if err != nil {
+			return nil, in.errorf(e, "interp: %v", err)
+		}
+		return v, nil
+	}
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return in.eval(e.X)
+	case *ast.Ident:
+		switch obj := in.Info.Uses[e].(type) {
+		case *types.Nil:
+			return nil, nil
+		case *types.Var:
+			v, err := in.variable(e, obj)
+			if err != nil {
+				return nil, err
+			}
+			return copyVal(v.val), nil
+		case *types.Func:
+			fd := in.funcs[obj.Name()]
+			if fd == nil {
+				return nil, in.unsupported(e)
+			}
+			return &closure{name: obj.Name(), node: fd, sig: obj.Type().(*types.Signature), body: fd.Body}, nil
+		}
+	case *ast.FuncLit:
+		return in.funcLit(e), nil
+	case *ast.CompositeLit:
+		return in.compositeLit(e)
+	case *ast.UnaryExpr:
+		return in.unary(e)
+	case *ast.StarExpr:
+		lv, err := in.lvalue(e)
+		if err != nil {
+			return nil, err
+		}
+		return in.lload(lv), nil
+	case *ast.SelectorExpr:
+		sel := in.Info.Selections[e]
+		if sel == nil || sel.Kind() != types.FieldVal {
+			return nil, in.unsupported(e)
+		}
+		x, err := in.eval(e.X)
+		if err != nil {
+			return nil, err
+		}
+		if p, ok := x.(pointer); ok {
+			lv, err := in.deref(e, p)
+			if err != nil {
+				return nil, err
+			}
+			x = in.lload(lv)
+		}
+		for _, i := range sel.Index() {
+			x = x.(structVal)[i]
+		}
+		return x, nil
+	case *ast.BinaryExpr:
+		return in.binaryExpr(e)
+	case *ast.CallExpr:
+		return in.callExpr(e)
+	}
+	return nil, in.unsupported(e)
+}
+
+// funcLit creates a closure that captures, by reference, every variable
+// in scope.
+func (in *interp) funcLit(e *ast.FuncLit) *closure {
+	f := in.mem.top()
+	env := make(map[*types.Var]*Var, len(f.env)+len(f.vars))
+	for k, v := range f.env {
+		env[k] = v
+	}
+	for k, v := range f.vars {
+		env[k] = v
+	}
+	return &closure{node: e, sig: in.Info.TypeOf(e).(*types.Signature), body: e.Body, env: env}
+}
+
+func (in *interp) compositeLit(e *ast.CompositeLit) (value, error) {
+	t := in.Info.TypeOf(e)
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil, in.unsupported(e)
+	}
+	s := zero(t).(structVal)
+	for i, elt := range e.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			name := kv.Key.(*ast.Ident).Name
+			for j := 0; j < st.NumFields(); j++ {
+				if st.Field(j).Name() == name {
+					i = j
+				}
+			}
+			elt = kv.Value
+		}
+		v, err := in.eval(elt)
+		if err != nil {
+			return nil, err
+		}
+		s[i] = in.assignable(v, st.Field(i).Type())
+	}
+	return s, nil
+}
+
+func (in *interp) unary(e *ast.UnaryExpr) (value, error) {
+	if e.Op == token.AND {
+		if lit, ok := ast.Unparen(e.X).(*ast.CompositeLit); ok {
+			v, err := in.eval(lit)
+			if err != nil {
+				return nil, err
+			}
+			t := in.Info.TypeOf(lit)
+			x := in.heapObject(e, t)
+			x.val = v
+			in.mem.emit(Event{Kind: EvAlloc, Frame: in.mem.top(), Var: x, Value: in.describe(v, t)})
+			return pointer{v: x}, nil
+		}
+		lv, err := in.lvalue(e.X)
+		if err != nil {
+			return nil, err
+		}
+		return pointer{v: lv.v, path: lv.path}, nil
+	}
+	x, err := in.eval(e.X)
+	if err != nil {
+		return nil, err
+	}
+	t := in.Info.TypeOf(e)
+	switch e.Op {
+	case token.ADD:
+		return x, nil
+	case token.NOT:
+		return !x.(bool), nil
+	case token.SUB:
+		switch x := x.(type) {
+		case int64:
+			return in.wrap(-x, t), nil
+		case uint64:
+			return in.wrap(-x, t), nil
+		case float64:
+			return in.wrap(-x, t), nil
+		}
+	case token.XOR:
+		switch x := x.(type) {
+		case int64:
+			return in.wrap(^x, t), nil
+		case uint64:
+			return in.wrap(^x, t), nil
+		}
+	}
+	return nil, in.unsupported(e)
+}
+
+// heapObject allocates an anonymous heap object of type t for new(T)
+// or &T{...}.
+func (in *interp) heapObject(n ast.Node, t types.Type) *Var {
+	name := in.text(n)
+	return in.mem.alloc(name, t, "allocated with "+strings.SplitN(name, "{", 2)[0])
+}
+
+func (in *interp) binaryExpr(e *ast.BinaryExpr) (value, error) {
+	x, err := in.eval(e.X)
+	if err != nil {
+		return nil, err
+	}
+	switch e.Op {
+	case token.LAND:
+		if !x.(bool) {
+			return false, nil
+		}
+		return in.eval(e.Y)
+	case token.LOR:
+		if x.(bool) {
+			return true, nil
+		}
+		return in.eval(e.Y)
+	}
+	y, err := in.eval(e.Y)
+	if err != nil {
+		return nil, err
+	}
+	return in.binary(e, e.Op, x, y, in.Info.TypeOf(e.X))
+}
+
+// binary applies op to operands of type t.
+func (in *interp) binary(n ast.Node, op token.Token, x, y value, t types.Type) (value, error) {
+	switch op {
+	case token.EQL, token.NEQ:
+		var eq bool
+		switch {
+		case x == nil || y == nil:
+			eq = isNil(x) && isNil(y)
+		default:
+			eq = equal(x, y)
+		}
+		return eq == (op == token.EQL), nil
+	case token.SHL, token.SHR:
+		var s uint64
+		switch y := y.(type) {
+		case int64:
+			if y < 0 {
+				return nil, in.errorf(n, "runtime error: negative shift amount")
+			}
+			s = uint64(y)
+		case uint64:
+			s = y
+		}
+		switch x := x.(type) {
+		case int64:
+			if op == token.SHL {
+				return in.wrap(x<<s, t), nil
+			}
+			return x >> s, nil
+		case uint64:
+			if op == token.SHL {
+				return in.wrap(x<<s, t), nil
+			}
+			return x >> s, nil
+		}
+	}
+	switch x := x.(type) {
+	case int64:
+		return in.intOp(n, op, x, y.(int64), t)
+	case uint64:
+		return in.uintOp(n, op, x, y.(uint64), t)
+	case float64:
+		return in.floatOp(n, op, x, y.(float64), t)
+	case string:
+		y := y.(string)
+		switch op {
+		case token.ADD:
+			return x + y, nil
+		case token.LSS:
+			return x < y, nil
+		case token.LEQ:
+			return x <= y, nil
+		case token.GTR:
+			return x > y, nil
+		case token.GEQ:
+			return x >= y, nil
+		}
+	}
+	return nil, in.errorf(n, "interp: unsupported operator %s on %s", op, t)
+}
+
+func isNil(v value) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case pointer:
+		return v.isNil()
+	case *closure:
+		return v == nil
+	}
+	return false
+}
+
+func (in *interp) intOp(n ast.Node, op token.Token, x, y int64, t types.Type) (value, error) {
+	switch op {
+	case token.ADD:
+		return in.wrap(x+y, t), nil
+	case token.SUB:
+		return in.wrap(x-y, t), nil
+	case token.MUL:
+		return in.wrap(x*y, t), nil
+	case token.QUO, token.REM:
+		if y == 0 {
+			return nil, in.errorf(n, "runtime error: integer divide by zero")
+		}
+		if op == token.QUO {
+			return in.wrap(x/y, t), nil
+		}
+		return in.wrap(x%y, t), nil
+	case token.AND:
+		return x & y, nil
+	case token.OR:
+		return x | y, nil
+	case token.XOR:
+		return x ^ y, nil
+	case token.AND_NOT:
+		return x &^ y, nil
+	case token.LSS:
+		return x < y, nil
+	case token.LEQ:
+		return x <= y, nil
+	case token.GTR:
+		return x > y, nil
+	case token.GEQ:
+		return x >= y, nil
+	}
+	return nil, in.errorf(n, "interp: unsupported operator %s", op)
+}
+
+func (in *interp) uintOp(n ast.Node, op token.Token, x, y uint64, t types.Type) (value, error) {
+	switch op {
+	case token.ADD:
+		return in.wrap(x+y, t), nil
+	case token.SUB:
+		return in.wrap(x-y, t), nil
+	case token.MUL:
+		return in.wrap(x*y, t), nil
+	case token.QUO, token.REM:
+		if y == 0 {
+			return nil, in.errorf(n, "runtime error: integer divide by zero")
+		}
+		if op == token.QUO {
+			return x / y, nil
+		}
+		return x % y, nil
+	case token.AND:
+		return x & y, nil
+	case token.OR:
+		return x | y, nil
+	case token.XOR:
+		return x ^ y, nil
+	case token.AND_NOT:
+		return x &^ y, nil
+	case token.LSS:
+		return x < y, nil
+	case token.LEQ:
+		return x <= y, nil
+	case token.GTR:
+		return x > y, nil
+	case token.GEQ:
+		return x >= y, nil
+	}
+	return nil, in.errorf(n, "interp: unsupported operator %s", op)
+}
+
+func (in *interp) floatOp(n ast.Node, op token.Token, x, y float64, t types.Type) (value, error) {
+	switch op {
+	case token.ADD:
+		return in.wrap(x+y, t), nil
+	case token.SUB:
+		return in.wrap(x-y, t), nil
+	case token.MUL:
+		return in.wrap(x*y, t), nil
+	case token.QUO:
+		return in.wrap(x/y, t), nil
+	case token.LSS:
+		return x < y, nil
+	case token.LEQ:
+		return x <= y, nil
+	case token.GTR:
+		return x > y, nil
+	case token.GEQ:
+		return x >= y, nil
+	}
+	return nil, in.errorf(n, "interp: unsupported operator %s", op)
+}
+
+func (in *interp) callExpr(e *ast.CallExpr) (value, error) {
+	if tv := in.Info.Types[e.Fun]; tv.IsType() {
+		x, err := in.eval(e.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		if x == nil {
+			return zero(tv.Type), nil
+		}
+		v, err := in.convert(x, in.Info.TypeOf(e.Args[0]), tv.Type)
+		if err != nil {
+			return nil, in.errorf(e, "interp: %v", err)
+		}
+		return v, nil
+	}
+	fun := ast.Unparen(e.Fun)
+	if id, ok := fun.(*ast.Ident); ok {
+		if b, ok := in.Info.Uses[id].(*types.Builtin); ok {
+			return in.builtin(e, b.Name())
+		}
+	}
+	var recv value
+	var c *closure
+	if sel, ok := fun.(*ast.SelectorExpr); ok {
+		if pkg, ok := in.Info.Uses[identOf(sel.X)].(*types.PkgName); ok {
+			return in.stdlib(e, pkg.Imported().Path()+"."+sel.Sel.Name)
+		}
+		if s := in.Info.Selections[sel]; s != nil && s.Kind() == types.MethodVal {
+			var err error
+			if recv, c, err = in.method(sel, s); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if c == nil {
+		f, err := in.eval(fun)
+		if err != nil {
+			return nil, err
+		}
+		if c = f.(*closure); c == nil {
+			return nil, in.errorf(e, "%v", errNilDeref)
+		}
+	}
+	if c.sig.Variadic() {
+		return nil, in.errorf(e, "interp: unsupported variadic call")
+	}
+	args, err := in.values(e.Args, c.sig.Params().Len())
+	if err != nil {
+		return nil, err
+	}
+	for i := range args {
+		args[i] = in.assignable(args[i], c.sig.Params().At(i).Type())
+	}
+	if id, ok := fun.(*ast.Ident); ok {
+		c = &closure{name: id.Name, node: c.node, sig: c.sig, body: c.body, env: c.env}
+	} else if c.name == "" {
+		c.name = "func"
+	}
+	res, err := in.call(c, recv, args, e.Args)
+	if err != nil {
+		return nil, err
+	}
+	switch len(res) {
+	case 0:
+		return nil, nil
+	case 1:
+		return res[0], nil
+	}
+	return tuple(res), nil
+}
+
+func identOf(e ast.Expr) *ast.Ident {
+	id, _ := e.(*ast.Ident)
+	return id
+}
+
+// method resolves a method call x.m(), taking the address of x or
+// dereferencing it as the receiver requires.
+func (in *interp) method(sel *ast.SelectorExpr, s *types.Selection) (value, *closure, error) {
+	fn := s.Obj().(*types.Func)
+	fd := in.methods[fn]
+	if fd == nil || len(s.Index()) > 1 {
+		return nil, nil, in.unsupported(sel)
+	}
+	sig := fn.Type().(*types.Signature)
+	_, wantPtr := sig.Recv().Type().Underlying().(*types.Pointer)
+	_, havePtr := in.Info.TypeOf(sel.X).Underlying().(*types.Pointer)
+	var recv value
+	var err error
+	switch {
+	case wantPtr && !havePtr:
+		var lv *lvalue
+		if lv, err = in.lvalue(sel.X); err == nil {
+			recv = pointer{v: lv.v, path: lv.path}
+		}
+	case !wantPtr && havePtr:
+		var p value
+		if p, err = in.eval(sel.X); err == nil {
+			var lv *lvalue
+			if lv, err = in.deref(sel, p.(pointer)); err == nil {
+				recv = in.lload(lv)
+			}
+		}
+	default:
+		recv, err = in.eval(sel.X)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	name := types.TypeString(sig.Recv().Type(), in.qualifier)
+	return recv, &closure{name: "(" + name + ")." + fn.Name(), node: fd, sig: sig, body: fd.Body}, nil
+}
+
+func (in *interp) builtin(e *ast.CallExpr, name string) (value, error) {
+	switch name {
+	case "println", "print":
+		parts := make([]string, len(e.Args))
+		for i, a := range e.Args {
+			v, err := in.eval(a)
+			if err != nil {
+				return nil, err
+			}
+			if parts[i], err = in.printArg(v, in.Info.TypeOf(a)); err != nil {
+				return nil, in.errorf(a, "%v", err)
+			}
+		}
+		if name == "println" {
+			in.print(strings.Join(parts, " ") + "\n")
+		} else {
+			in.print(strings.Join(parts, ""))
+		}
+		return nil, nil
+	case "new":
+		t := in.Info.TypeOf(e.Args[0])
+		x := in.heapObject(e, t)
+		in.mem.emit(Event{Kind: EvAlloc, Frame: in.mem.top(), Var: x, Value: in.describe(x.val, t)})
+		return pointer{v: x}, nil
+	case "len":
+		s, err := in.eval(e.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := s.(string); ok {
+			return int64(len(s)), nil
+		}
+	}
+	return nil, in.errorf(e, "interp: unsupported builtin %s", name)
+}
+
+// stdlib implements the few standard library functions lessons use.
+func (in *interp) stdlib(e *ast.CallExpr, name string) (value, error) {
+	switch name {
+	case "fmt.Println", "fmt.Print", "fmt.Sprint", "fmt.Sprintln":
+	default:
+		return nil, in.errorf(e, "interp: unsupported call to %s", name)
+	}
+	var b strings.Builder
+	prevString := false
+	for i, a := range e.Args {
+		v, err := in.eval(a)
+		if err != nil {
+			return nil, err
+		}
+		_, isString := v.(string)
+		if strings.HasSuffix(name, "ln") && i > 0 || !strings.HasSuffix(name, "ln") && i > 0 && !isString && !prevString {
+			b.WriteByte(' ')
+		}
+		prevString = isString
+		b.WriteString(in.format(v, in.Info.TypeOf(a), true))
+	}
+	if strings.HasSuffix(name, "ln") {
+		b.WriteByte('\n')
+	}
+	if strings.HasPrefix(name, "fmt.Sprint") {
+		return b.String(), nil
+	}
+	in.print(b.String())
+	return nil, nil
+}
+
+// print writes program output and records it.
+func (in *interp) print(s string) {
+	fmt.Fprint(in.out, s)
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if line != "" {
+			in.mem.emit(Event{Kind: EvOutput, Frame: in.mem.top(), Text: strings.TrimSuffix(line, "\n")})
+		}
+	}
+}
@@ -0,0 +1,432 @@
+// Package interp is a tracing interpreter for the subset of Go used by the
+// lessons: variables, constants, conversions, structs, closures, pointers,
+// println and fmt.Println.
+//
+// Instead of real memory, the interpreter keeps a simulated stack and heap
+// with invented addresses (see memory.go), and records every allocation,
+// store, call and return. The recorded events can be rendered as the
+// memory-flow diagrams used in the README, and they are identical on every
+// run.
+//
+// The model of where variables live is deliberately simpler than the
+// compiler's escape analysis: a variable is on the heap when a closure
+// captures it or its address is returned, and new(T) and &T{} always
+// allocate on the heap. Everything else lives in the frame of the function
+// that declares it.
+package interp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"go/version"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// maxDepth bounds recursion so that runaway lessons fail instead of
+// exhausting the real stack.
+const maxDepth = 1000
+
+// maxSteps and maxEvents bound a run, so that a lesson that never ends,
+// such as "for { n++ }", fails instead of running forever and recording
+// events until memory runs out. A step is a statement or a loop
+// iteration.
+const (
+	maxSteps  = 10_000_000
+	maxEvents = 100_000
+)
+
+// ErrLimit is wrapped by the error Run returns when a run exceeds
+// maxSteps or maxEvents.
+var ErrLimit = errors.New("interp: run too long")
+
+// Program is a type-checked package ready to be interpreted.
+type Program struct {
+	Fset  *token.FileSet
+	Pkg   *types.Package
+	Info  *types.Info
+	Files []*ast.File
+
+	// GoVersion is the toolchain whose println output Run reproduces; it
+	// decides how floats print. Load sets it to runtime.Version().
+	GoVersion string
+
+	funcs   map[string]*ast.FuncDecl
+	methods map[*types.Func]*ast.FuncDecl
+	inits   map[*types.Var]*types.Initializer
+	heap    map[*types.Var]string // variables the model places on the heap
+}
+
+// Load parses and type-checks the Go package in dir.
+func Load(dir string) (*Program, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "gc", nil)}
+	pkg, err := conf.Check(bp.ImportPath, fset, files, info)
+	if err != nil {
+		return nil, err
+	}
+	p := &Program{
+		Fset:      fset,
+		Pkg:       pkg,
+		Info:      info,
+		Files:     files,
+		GoVersion: runtime.Version(),
+		funcs:     make(map[string]*ast.FuncDecl),
+		methods:   make(map[*types.Func]*ast.FuncDecl),
+		inits:     make(map[*types.Var]*types.Initializer),
+		heap:      make(map[*types.Var]string),
+	}
+	for _, init := range info.InitOrder {
+		for _, v := range init.Lhs {
+			p.inits[v] = init
+		}
+	}
+	for _, f := range files {
+		for _, d := range f.Decls {
+			fd, ok := d.(*ast.FuncDecl)
+			if !ok || fd.Body == nil {
+				continue
+			}
+			if fd.Recv != nil {
+				p.methods[info.Defs[fd.Name].(*types.Func)] = fd
+			} else if fd.Name.Name != "init" {
+				p.funcs[fd.Name.Name] = fd
+			}
+		}
+		p.findHeap(f)
+	}
+	return p, nil
+}
+
+// findHeap records the local variables that the model places on the heap.
+func (p *Program) findHeap(f *ast.File) {
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			ast.Inspect(n.Body, func(m ast.Node) bool {
+				id, ok := m.(*ast.Ident)
+				if !ok {
+					return true
+				}
+				v, ok := p.Info.Uses[id].(*types.Var)
+				if ok && p.isLocal(v) && (v.Pos() < n.Pos() || v.Pos() >= n.End()) {
+					p.heap[v] = "captured by closure"
+				}
+				return true
+			})
+		case *ast.ReturnStmt:
+			for _, r := range n.Results {
+				u, ok := ast.Unparen(r).(*ast.UnaryExpr)
+				if !ok || u.Op != token.AND {
+					continue
+				}
+				if id, ok := ast.Unparen(u.X).(*ast.Ident); ok {
+					if v, ok := p.Info.Uses[id].(*types.Var); ok && p.isLocal(v) {
+						p.heap[v] = "address returned to caller"
+					}
+				}
+			}
+		}
+		return true
+	})
+}
+
+func (p *Program) isLocal(v *types.Var) bool {
+	return !v.IsField() && v.Parent() != p.Pkg.Scope() && v.Parent() != nil
+}
+
+// Funcs returns the names of the package's top-level functions that take
+// no arguments and return no results; these are the ones Run accepts.
+func (p *Program) Funcs() []string {
+	var names []string
+	for name, fd := range p.funcs {
+		if fd.Type.Params.NumFields() == 0 && fd.Type.Results.NumFields() == 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Trace is the record of one run.
+type Trace struct {
+	Func   string
+	Events []Event
+}
+
+// Run interprets the function name, which must take no arguments and
+// return no results, writing the program's output to out. The run stops
+// with ctx's error when ctx is done, and with an error wrapping ErrLimit
+// when it takes too many steps. The trace is returned even when the run
+// fails part way.
+func (p *Program) Run(ctx context.Context, name string, out io.Writer) (*Trace, error) {
+	fd, ok := p.funcs[name]
+	if !ok {
+		return nil, fmt.Errorf("no function %s in package %s", name, p.Pkg.Name())
+	}
+	if fd.Type.Params.NumFields() != 0 || fd.Type.Results.NumFields() != 0 {
+		return nil, fmt.Errorf("%s takes arguments or returns results", name)
+	}
+	in := &interp{
+		Program:   p,
+		ctx:       ctx,
+		oldFloats: version.IsValid(p.GoVersion) && version.Compare(p.GoVersion, "go1.26") < 0,
+		sizes:     types.SizesFor("gc", "amd64"), // diagrams always use the x64 layout
+		out:       out,
+		globals:   make(map[*types.Var]*Var),
+		code:      make(map[ast.Node]uint64),
+		qualifier: types.RelativeTo(p.Pkg),
+	}
+	in.mem = newMemory(in.sizes)
+	c := &closure{name: name, node: fd, sig: p.Info.Defs[fd.Name].Type().(*types.Signature), body: fd.Body}
+	_, err := in.call(c, nil, nil, nil)
+	return &Trace{Func: name, Events: in.mem.events}, err
+}
+
+// closure is a function value: a top-level function, a method, or a
+// function literal together with the variables it captured.
+type closure struct {
+	name string
+	node ast.Node
+	sig  *types.Signature
+	body *ast.BlockStmt
+	env  map[*types.Var]*Var
+}
+
+type interp struct {
+	*Program
+	ctx       context.Context
+	steps     int
+	oldFloats bool // println floats as +1.500000e+000, before Go 1.26
+	sizes     types.Sizes
+	mem       *memory
+	out       io.Writer
+	globals   map[*types.Var]*Var
+	code      map[ast.Node]uint64
+	qualifier types.Qualifier
+}
+
+// codeAddr returns the simulated address of a function's code.
+func (in *interp) codeAddr(n ast.Node) uint64 {
+	a, ok := in.code[n]
+	if !ok {
+		a = 0x401000 + 0x40*uint64(len(in.code))
+		in.code[n] = a
+	}
+	return a
+}
+
+// step counts a statement or loop iteration at n against the limits and
+// checks, every 1024 steps, whether the run was canceled.
+func (in *interp) step(n ast.Node) error {
+	in.steps++
+	switch {
+	case in.steps > maxSteps:
+		return fmt.Errorf("%w: %v", ErrLimit, in.errorf(n, "more than %d steps", maxSteps))
+	case len(in.mem.events) > maxEvents:
+		return fmt.Errorf("%w: %v", ErrLimit, in.errorf(n, "more than %d memory events", maxEvents))
+	case in.steps%1024 == 0:
+		return in.ctx.Err()
+	}
+	return nil
+}
+
+// errorf reports an error at the position of n.
+func (in *interp) errorf(n ast.Node, format string, args ...any) error {
+	pos := in.Fset.Position(n.Pos())
+	pos.Filename = filepath.Base(pos.Filename)
+	return fmt.Errorf("%s: %s", pos, fmt.Sprintf(format, args...))
+}
+
+func (in *interp) unsupported(n ast.Node) error {
+	return in.errorf(n, "interp: unsupported %s", strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast."))
+}
+
+// text returns the first line of the source of n, for event descriptions.
+func (in *interp) text(n ast.Node) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, in.Fset, n)
+	s, _, cut := strings.Cut(buf.String(), "\n")
+	if cut {
+		s += " ..."
+	}
+	return s
+}
+
+// call runs c with the given arguments in a new frame. recv is the
+// receiver for method calls, and argExprs are the caller's argument
+// expressions, used to report what the call did to the caller's
+// variables.
+func (in *interp) call(c *closure, recv value, args []value, argExprs []ast.Expr) ([]value, error) {
+	if len(in.mem.stack) >= maxDepth {
+		return nil, in.errorf(c.node, "runtime: goroutine stack exceeds %d frames", maxDepth)
+	}
+	caller := in.mem.top()
+	var watch []Watch
+	if caller != nil {
+		for _, v := range in.mentioned(caller, argExprs) {
+			watch = append(watch, Watch{Var: v, Before: in.describe(v.val, v.Type)})
+		}
+	}
+
+	f := in.mem.push(c.name, c.name+in.params(c.sig))
+	f.env = c.env
+	f.sig = c.sig
+	in.mem.emit(Event{Kind: EvCall, Frame: f, Text: f.Sig})
+
+	if r := c.sig.Recv(); r != nil {
+		in.bind(f, r, recv, true)
+	}
+	for i := 0; i < c.sig.Params().Len(); i++ {
+		in.bind(f, c.sig.Params().At(i), args[i], true)
+	}
+	results := c.sig.Results()
+	named := results.Len() > 0 && results.At(0).Name() != ""
+	if named {
+		for i := 0; i < results.Len(); i++ {
+			in.bind(f, results.At(i), nil, false)
+		}
+	}
+
+	_, err := in.block(c.body.List)
+	if err == nil && f.result == nil && named {
+		for i := 0; i < results.Len(); i++ {
+			v := f.lookup(results.At(i))
+			f.result = append(f.result, copyVal(v.val))
+		}
+	}
+	in.mem.pop()
+
+	ret := Event{Kind: EvReturn, Frame: caller, Text: f.Sig}
+	for i, r := range f.result {
+		ret.Ret = append(ret.Ret, in.describe(r, results.At(i).Type()))
+	}
+	for _, w := range watch {
+		w.After = in.describe(w.Var.val, w.Var.Type)
+		ret.Watch = append(ret.Watch, w)
+	}
+	if caller != nil {
+		in.mem.emit(ret)
+	}
+	return f.result, err
+}
+
+// bind allocates a parameter or result variable in f. Parameters are
+// copies of the caller's arguments.
+func (in *interp) bind(f *Frame, obj *types.Var, v value, param bool) {
+	name := obj.Name()
+	if name == "" || name == "_" {
+		name = "_"
+	}
+	x := in.mem.local(f, obj, name, obj.Type(), in.heap[obj])
+	if v != nil {
+		x.val = v
+	}
+	in.mem.emit(Event{Kind: EvAlloc, Frame: f, Var: x, Value: in.describe(x.val, x.Type), Copy: param})
+}
+
+// params formats a signature's parameter list with names, e.g.
+// "(num *int)".
+func (in *interp) params(sig *types.Signature) string {
+	var parts []string
+	for i := 0; i < sig.Params().Len(); i++ {
+		p := sig.Params().At(i)
+		parts = append(parts, strings.TrimSpace(p.Name()+" "+types.TypeString(p.Type(), in.qualifier)))
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// mentioned returns the variables of frame f named in exprs, including
+// those whose address is taken.
+func (in *interp) mentioned(f *Frame, exprs []ast.Expr) []*Var {
+	var vars []*Var
+	seen := make(map[*Var]bool)
+	for _, e := range exprs {
+		ast.Inspect(e, func(n ast.Node) bool {
+			if _, ok := n.(*ast.FuncLit); ok {
+				return false
+			}
+			id, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			if obj, ok := in.Info.Uses[id].(*types.Var); ok {
+				if v := f.lookup(obj); v != nil && !seen[v] {
+					seen[v] = true
+					vars = append(vars, v)
+				}
+			}
+			return true
+		})
+	}
+	return vars
+}
+
+// variable returns the variable for obj visible from the current frame,
+// initializing package-level variables on first use.
+func (in *interp) variable(n ast.Node, obj *types.Var) (*Var, error) {
+	if f := in.mem.top(); f != nil {
+		if v := f.lookup(obj); v != nil {
+			return v, nil
+		}
+	}
+	if obj.Parent() != in.Pkg.Scope() {
+		return nil, in.errorf(n, "interp: variable %s is not in scope", obj.Name())
+	}
+	if v, ok := in.globals[obj]; ok {
+		return v, nil
+	}
+	v := in.mem.global(obj.Name(), obj.Type())
+	in.globals[obj] = v
+	if init, ok := in.inits[obj]; ok {
+		vals, err := in.values([]ast.Expr{init.Rhs}, len(init.Lhs))
+		if err != nil {
+			return nil, err
+		}
+		for i, lhs := range init.Lhs {
+			if lhs == obj {
+				v.val = in.assignable(vals[i], obj.Type())
+			}
+		}
+	}
+	in.mem.emit(Event{Kind: EvAlloc, Var: v, Value: in.describe(v.val, v.Type)})
+	return v, nil
+}
+
+// assignable converts an untyped nil to the zero value of t.
+func (in *interp) assignable(v value, t types.Type) value {
+	if v == nil {
+		return zero(t)
+	}
+	return v
+}
+
+var errNilDeref = errors.New("runtime error: invalid memory address or nil pointer dereference")
@@ -0,0 +1,80 @@
+package interp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSrc = `package main
+
+func main() {}
+
+func spin() {
+	n := 0
+	for {
+		n++
+	}
+}
+
+func floats() {
+	println(1.5, 3.14, 1e21, float32(0.1))
+}
+`
+
+func load(t *testing.T) *Program {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module p\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(testSrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestRunStepLimit(t *testing.T) {
+	p := load(t)
+	_, err := p.Run(context.Background(), "spin", new(strings.Builder))
+	if !errors.Is(err, ErrLimit) {
+		t.Fatalf("Run(spin) = %v, want ErrLimit", err)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	p := load(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := p.Run(ctx, "spin", new(strings.Builder))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run(spin) with a canceled context = %v, want context.Canceled", err)
+	}
+}
+
+func TestPrintFloat(t *testing.T) {
+	for _, tt := range []struct {
+		version string
+		want    string
+	}{
+		{version: "go1.25.3", want: "+1.500000e+000 +3.140000e+000 +1.000000e+021 +1.000000e-001\n"},
+		{version: "go1.26.0", want: "1.5 3.14 1e+21 0.1\n"},
+	} {
+		p := load(t)
+		p.GoVersion = tt.version
+		var out strings.Builder
+		if _, err := p.Run(context.Background(), "floats", &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("%s: println printed %q, want %q", tt.version, out.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package interp
+
+import (
+	"fmt"
+	"go/types"
+)
+
+// Simulated address space. Addresses are invented by the interpreter so
+// that diagrams are identical on every run and every machine:
+//
+//	0x1000 * (depth+1)   stack frame of the call at that depth
+//	0x500000             package-level variables (data segment)
+//	0xc000000000         zerobase, shared by every zero-size heap object
+//	0xc000010000         first heap object
+const (
+	frameStride = 0x1000
+	dataBase    = 0x500000
+	zeroBase    = 0xc000000000
+	heapBase    = 0xc000010000
+)
+
+// Var is a variable in the simulated memory: a local, a parameter, a
+// package-level variable or an anonymous heap object.
+type Var struct {
+	Name   string
+	Type   types.Type
+	Addr   uint64
+	Frame  *Frame // nil for package-level variables and heap objects
+	Heap   bool
+	Reason string // why the variable lives on the heap
+
+	val value
+}
+
+// Frame is one activation record on the simulated stack.
+type Frame struct {
+	Func  string // function name, e.g. "pointer" or "increment"
+	Sig   string // name with parameters, e.g. "increment(num int)"
+	Depth int
+	Base  uint64
+	Vars  []*Var
+
+	next   uint64 // next free offset from Base
+	vars   map[*types.Var]*Var
+	env    map[*types.Var]*Var // variables captured by the running closure
+	sig    *types.Signature
+	result []value
+}
+
+func (f *Frame) lookup(obj *types.Var) *Var {
+	if v, ok := f.vars[obj]; ok {
+		return v
+	}
+	return f.env[obj]
+}
+
+// EventKind identifies what happened in an Event.
+type EventKind int
+
+const (
+	EvAlloc  EventKind = iota // a variable was allocated
+	EvStore                   // a variable, or a field of one, was written
+	EvCall                    // a function was called and a frame pushed
+	EvReturn                  // a frame was popped
+	EvOutput                  // the program printed a line
+)
+
+// Event is one step of the recorded memory flow. Values are formatted at
+// the time of the event, so later writes do not change earlier events.
+type Event struct {
+	Kind  EventKind
+	Frame *Frame // executing frame; for EvReturn the frame returned to
+	Var   *Var
+	Path  string // field path for stores into structs, e.g. ".pi"
+	Value string // value after the event
+	Text  string // source of the statement, or the printed line
+	Copy  bool   // EvAlloc: the variable is a parameter copy
+	Via   bool   // EvStore: the write went through a pointer
+	Watch []Watch
+	Ret   []string // EvReturn: formatted results
+}
+
+// Watch records a caller variable that was mentioned in the arguments of
+// a call, with its value before and after the call.
+type Watch struct {
+	Var           *Var
+	Before, After string
+}
+
+// memory is the simulated stack and heap of one run.
+type memory struct {
+	sizes  types.Sizes
+	stack  []*Frame
+	data   uint64
+	heap   uint64
+	events []Event
+}
+
+func newMemory(sizes types.Sizes) *memory {
+	return &memory{sizes: sizes, data: dataBase, heap: heapBase}
+}
+
+func (m *memory) top() *Frame {
+	if len(m.stack) == 0 {
+		return nil
+	}
+	return m.stack[len(m.stack)-1]
+}
+
+func (m *memory) push(name, sig string) *Frame {
+	d := len(m.stack)
+	f := &Frame{
+		Func:  name,
+		Sig:   sig,
+		Depth: d,
+		Base:  frameStride * uint64(d+1),
+		vars:  make(map[*types.Var]*Var),
+	}
+	m.stack = append(m.stack, f)
+	return f
+}
+
+func (m *memory) pop() {
+	m.stack = m.stack[:len(m.stack)-1]
+}
+
+// local allocates a variable in frame f, or on the heap when reason is
+// not empty.
+func (m *memory) local(f *Frame, obj *types.Var, name string, t types.Type, reason string) *Var {
+	var v *Var
+	if reason != "" {
+		v = m.alloc(name, t, reason)
+	} else {
+		off := align(f.next, uint64(m.sizes.Alignof(t)))
+		v = &Var{Name: name, Type: t, Addr: f.Base + off, Frame: f}
+		f.next = off + uint64(m.sizes.Sizeof(t))
+	}
+	v.val = zero(t)
+	f.Vars = append(f.Vars, v)
+	if obj != nil {
+		f.vars[obj] = v
+	}
+	return v
+}
+
+// global allocates a package-level variable in the data segment.
+func (m *memory) global(name string, t types.Type) *Var {
+	m.data = align(m.data, uint64(m.sizes.Alignof(t)))
+	v := &Var{Name: name, Type: t, Addr: m.data, val: zero(t)}
+	m.data += uint64(m.sizes.Sizeof(t))
+	return v
+}
+
+// alloc allocates an object on the heap. Like the runtime, every
+// zero-size allocation returns the same address.
+func (m *memory) alloc(name string, t types.Type, reason string) *Var {
+	v := &Var{Name: name, Type: t, Heap: true, Reason: reason, val: zero(t)}
+	size := uint64(m.sizes.Sizeof(t))
+	if size == 0 {
+		v.Addr = zeroBase
+		return v
+	}
+	v.Addr = m.heap
+	m.heap += align(size, 8)
+	return v
+}
+
+// addr returns the address of the value reached by following path from v.
+func (m *memory) addr(v *Var, path []int) uint64 {
+	a := v.Addr
+	t := v.Type
+	for _, i := range path {
+		st := t.Underlying().(*types.Struct)
+		fields := make([]*types.Var, st.NumFields())
+		for j := range fields {
+			fields[j] = st.Field(j)
+		}
+		a += uint64(m.sizes.Offsetsof(fields)[i])
+		t = st.Field(i).Type()
+	}
+	return a
+}
+
+func (m *memory) emit(e Event) {
+	m.events = append(m.events, e)
+}
+
+func align(x, a uint64) uint64 {
+	if a <= 1 {
+		return x
+	}
+	return (x + a - 1) / a * a
+}
+
+func hex(a uint64) string {
+	return fmt.Sprintf("%#x", a)
+}
@@ -0,0 +1,310 @@
+package interp
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// ctrl says how a statement finished.
+type ctrl int
+
+const (
+	ctrlNext ctrl = iota
+	ctrlReturn
+	ctrlBreak
+	ctrlContinue
+)
+
+func (in *interp) block(list []ast.Stmt) (ctrl, error) {
+	for _, s := range list {
+		c, err := in.stmt(s)
+		if err != nil || c != ctrlNext {
+			return c, err
+		}
+	}
+	return ctrlNext, nil
+}
+
+func (in *interp) stmt(s ast.Stmt) (ctrl, error) {
+	if err := in.step(s); err != nil {
+		return ctrlNext, err
+	}
+	switch s := s.(type) {
+	case *ast.EmptyStmt:
+		return ctrlNext, nil
+	case *ast.DeclStmt:
+		return ctrlNext, in.decl(s.Decl.(*ast.GenDecl))
+	case *ast.ExprStmt:
+		_, err := in.eval(s.X)
+		return ctrlNext, err
+	case *ast.AssignStmt:
+		return ctrlNext, in.assign(s)
+	case *ast.IncDecStmt:
+		lv, err := in.lvalue(s.X)
+		if err != nil {
+			return ctrlNext, err
+		}
+		op := token.ADD
+		if s.Tok == token.DEC {
+			op = token.SUB
+		}
+		one, _ := in.convert(int64(1), types.Typ[types.Int], in.Info.TypeOf(s.X))
+		v, err := in.binary(s, op, in.lload(lv), one, in.Info.TypeOf(s.X))
+		if err != nil {
+			return ctrlNext, err
+		}
+		in.store(lv, v, s)
+		return ctrlNext, nil
+	case *ast.BlockStmt:
+		return in.block(s.List)
+	case *ast.IfStmt:
+		if s.Init != nil {
+			if _, err := in.stmt(s.Init); err != nil {
+				return ctrlNext, err
+			}
+		}
+		cond, err := in.eval(s.Cond)
+		if err != nil {
+			return ctrlNext, err
+		}
+		if cond.(bool) {
+			return in.block(s.Body.List)
+		}
+		if s.Else != nil {
+			return in.stmt(s.Else)
+		}
+		return ctrlNext, nil
+	case *ast.ForStmt:
+		return in.forStmt(s)
+	case *ast.BranchStmt:
+		if s.Label != nil {
+			return ctrlNext, in.unsupported(s)
+		}
+		switch s.Tok {
+		case token.BREAK:
+			return ctrlBreak, nil
+		case token.CONTINUE:
+			return ctrlContinue, nil
+		}
+		return ctrlNext, in.unsupported(s)
+	case *ast.ReturnStmt:
+		return ctrlReturn, in.ret(s)
+	}
+	return ctrlNext, in.unsupported(s)
+}
+
+// decl executes a local var, const or type declaration. Constants and
+// types need no storage: the type checker already knows them.
+func (in *interp) decl(d *ast.GenDecl) error {
+	if d.Tok != token.VAR {
+		return nil
+	}
+	for _, spec := range d.Specs {
+		vs := spec.(*ast.ValueSpec)
+		var vals []value
+		if len(vs.Values) > 0 {
+			var err error
+			if vals, err = in.values(vs.Values, len(vs.Names)); err != nil {
+				return err
+			}
+		}
+		for i, id := range vs.Names {
+			var v value
+			if vals != nil {
+				v = vals[i]
+			}
+			in.define(id, v)
+		}
+	}
+	return nil
+}
+
+// define allocates the variable declared by id and initializes it.
+func (in *interp) define(id *ast.Ident, v value) {
+	obj, ok := in.Info.Defs[id].(*types.Var)
+	if !ok {
+		return
+	}
+	f := in.mem.top()
+	x := in.mem.local(f, obj, id.Name, obj.Type(), in.heap[obj])
+	if v != nil {
+		x.val = in.assignable(v, obj.Type())
+	}
+	if c, ok := x.val.(*closure); ok && c != nil && c.name == "" {
+		c.name = id.Name
+	}
+	in.mem.emit(Event{Kind: EvAlloc, Frame: f, Var: x, Value: in.describe(x.val, x.Type)})
+}
+
+// values evaluates exprs, unpacking a single multi-value call into n
+// values.
+func (in *interp) values(exprs []ast.Expr, n int) ([]value, error) {
+	if len(exprs) == 1 && n > 1 {
+		v, err := in.eval(exprs[0])
+		if err != nil {
+			return nil, err
+		}
+		return v.(tuple), nil
+	}
+	vals := make([]value, len(exprs))
+	for i, e := range exprs {
+		v, err := in.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+func (in *interp) assign(s *ast.AssignStmt) error {
+	switch s.Tok {
+	case token.DEFINE:
+		vals, err := in.values(s.Rhs, len(s.Lhs))
+		if err != nil {
+			return err
+		}
+		for i, lhs := range s.Lhs {
+			id := lhs.(*ast.Ident)
+			if id.Name == "_" {
+				continue
+			}
+			if in.Info.Defs[id] != nil {
+				in.define(id, vals[i])
+				continue
+			}
+			lv, err := in.lvalue(id)
+			if err != nil {
+				return err
+			}
+			in.store(lv, vals[i], s)
+		}
+		return nil
+	case token.ASSIGN:
+		lvs := make([]*lvalue, len(s.Lhs))
+		for i, lhs := range s.Lhs {
+			if id, ok := lhs.(*ast.Ident); ok && id.Name == "_" {
+				continue
+			}
+			lv, err := in.lvalue(lhs)
+			if err != nil {
+				return err
+			}
+			lvs[i] = lv
+		}
+		vals, err := in.values(s.Rhs, len(s.Lhs))
+		if err != nil {
+			return err
+		}
+		for i, lv := range lvs {
+			if lv != nil {
+				in.store(lv, vals[i], s)
+			}
+		}
+		return nil
+	}
+	op, ok := assignOps[s.Tok]
+	if !ok {
+		return in.unsupported(s)
+	}
+	lv, err := in.lvalue(s.Lhs[0])
+	if err != nil {
+		return err
+	}
+	y, err := in.eval(s.Rhs[0])
+	if err != nil {
+		return err
+	}
+	v, err := in.binary(s, op, in.lload(lv), y, in.Info.TypeOf(s.Lhs[0]))
+	if err != nil {
+		return err
+	}
+	in.store(lv, v, s)
+	return nil
+}
+
+var assignOps = map[token.Token]token.Token{
+	token.ADD_ASSIGN:     token.ADD,
+	token.SUB_ASSIGN:     token.SUB,
+	token.MUL_ASSIGN:     token.MUL,
+	token.QUO_ASSIGN:     token.QUO,
+	token.REM_ASSIGN:     token.REM,
+	token.AND_ASSIGN:     token.AND,
+	token.OR_ASSIGN:      token.OR,
+	token.XOR_ASSIGN:     token.XOR,
+	token.SHL_ASSIGN:     token.SHL,
+	token.SHR_ASSIGN:     token.SHR,
+	token.AND_NOT_ASSIGN: token.AND_NOT,
+}
+
+func (in *interp) forStmt(s *ast.ForStmt) (ctrl, error) {
+	if s.Init != nil {
+		if _, err := in.stmt(s.Init); err != nil {
+			return ctrlNext, err
+		}
+	}
+	for {
+		if err := in.step(s); err != nil {
+			return ctrlNext, err
+		}
+		if s.Cond != nil {
+			cond, err := in.eval(s.Cond)
+			if err != nil {
+				return ctrlNext, err
+			}
+			if !cond.(bool) {
+				return ctrlNext, nil
+			}
+		}
+		c, err := in.block(s.Body.List)
+		if err != nil || c == ctrlReturn {
+			return c, err
+		}
+		if c == ctrlBreak {
+			return ctrlNext, nil
+		}
+		in.renewLoopVars(s)
+		if s.Post != nil {
+			if _, err := in.stmt(s.Post); err != nil {
+				return ctrlNext, err
+			}
+		}
+	}
+}
+
+// renewLoopVars gives each iteration its own copy of the loop variables
+// that closures capture, as Go 1.22 and later do.
+func (in *interp) renewLoopVars(s *ast.ForStmt) {
+	init, ok := s.Init.(*ast.AssignStmt)
+	if !ok || init.Tok != token.DEFINE {
+		return
+	}
+	f := in.mem.top()
+	for _, lhs := range init.Lhs {
+		obj, ok := in.Info.Defs[lhs.(*ast.Ident)].(*types.Var)
+		if !ok || in.heap[obj] == "" {
+			continue
+		}
+		old := f.vars[obj]
+		x := in.mem.local(f, obj, old.Name, old.Type, in.heap[obj])
+		x.val = copyVal(old.val)
+	}
+}
+
+func (in *interp) ret(s *ast.ReturnStmt) error {
+	f := in.mem.top()
+	if len(s.Results) == 0 {
+		return nil
+	}
+	sig := f.sig
+	vals, err := in.values(s.Results, sig.Results().Len())
+	if err != nil {
+		return err
+	}
+	f.result = make([]value, len(vals))
+	for i, v := range vals {
+		f.result[i] = in.assignable(v, sig.Results().At(i).Type())
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package interp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"basics/internal/lesson"
+)
+
+// TestTraceGolden interprets lessons of the module and compares their
+// output and memory-flow diagram, as "basics trace" prints them, with
+// testdata/trace_<name>.golden.
+func TestTraceGolden(t *testing.T) {
+	const dir = "../.."
+	lessons, err := lesson.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"main", "pointer"} {
+		t.Run(name, func(t *testing.T) {
+			l, err := lesson.Find(lessons, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			tr, err := p.Run(context.Background(), l.Func, &out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out.WriteString("\nMemory flow:\n\n")
+			if err := tr.WriteDiagram(&out); err != nil {
+				t.Fatal(err)
+			}
+			diff, err := lesson.Check(dir, "trace_"+name, []byte(out.String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff != "" {
+				t.Errorf("trace differs from %s (regenerate with \"basics trace %s\"):\n%s", lesson.GoldenPath(dir, "trace_"+name), name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,377 @@
+package interp
+
+import (
+	"fmt"
+	"go/constant"
+	"go/types"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// value is a Go value in the interpreter. The dynamic representation
+// depends only on the underlying type:
+//
+//	bool                       bool
+//	int, int8, ..., int64      int64
+//	uint, uint8, ..., uintptr  uint64
+//	float32, float64           float64 (rounded to float32 when needed)
+//	string                     string
+//	struct                     structVal
+//	pointer                    pointer
+//	func                       *closure
+type value any
+
+// structVal holds the fields of a struct in declaration order. It is
+// copied on every load so that assignment has value semantics.
+type structVal []value
+
+// pointer refers to a variable, or to a field of one via path. The zero
+// pointer is nil.
+type pointer struct {
+	v    *Var
+	path []int
+}
+
+func (p pointer) isNil() bool { return p.v == nil }
+
+func zero(t types.Type) value {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return false
+		case u.Info()&types.IsUnsigned != 0:
+			return uint64(0)
+		case u.Info()&types.IsInteger != 0:
+			return int64(0)
+		case u.Info()&types.IsFloat != 0:
+			return float64(0)
+		case u.Info()&types.IsString != 0:
+			return ""
+		}
+	case *types.Struct:
+		s := make(structVal, u.NumFields())
+		for i := range s {
+			s[i] = zero(u.Field(i).Type())
+		}
+		return s
+	case *types.Pointer:
+		return pointer{}
+	case *types.Signature:
+		return (*closure)(nil)
+	}
+	return nil
+}
+
+func copyVal(v value) value {
+	s, ok := v.(structVal)
+	if !ok {
+		return v
+	}
+	c := make(structVal, len(s))
+	for i, f := range s {
+		c[i] = copyVal(f)
+	}
+	return c
+}
+
+func equal(a, b value) bool {
+	switch a := a.(type) {
+	case structVal:
+		b := b.(structVal)
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case pointer:
+		b := b.(pointer)
+		if a.v != b.v || len(a.path) != len(b.path) {
+			return false
+		}
+		for i := range a.path {
+			if a.path[i] != b.path[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func basic(t types.Type) *types.Basic {
+	b, _ := t.Underlying().(*types.Basic)
+	return b
+}
+
+// fromConst converts a constant computed by the type checker to a value
+// of type t. Untyped constants take their default type.
+func fromConst(c constant.Value, t types.Type) (value, error) {
+	t = types.Default(t)
+	b := basic(t)
+	if b == nil {
+		return nil, fmt.Errorf("constant of type %s", t)
+	}
+	switch {
+	case b.Info()&types.IsBoolean != 0:
+		return constant.BoolVal(c), nil
+	case b.Info()&types.IsString != 0:
+		return constant.StringVal(c), nil
+	case b.Info()&types.IsUnsigned != 0:
+		u, _ := constant.Uint64Val(constant.ToInt(c))
+		return u, nil
+	case b.Info()&types.IsInteger != 0:
+		i, _ := constant.Int64Val(constant.ToInt(c))
+		return i, nil
+	case b.Info()&types.IsFloat != 0:
+		f, _ := constant.Float64Val(constant.ToFloat(c))
+		if b.Kind() == types.Float32 {
+			f = float64(float32(f))
+		}
+		return f, nil
+	}
+	return nil, fmt.Errorf("constant of type %s", t)
+}
+
+// wrap truncates an integer result to the width of type t.
+func (in *interp) wrap(v value, t types.Type) value {
+	bits := 8 * in.sizes.Sizeof(t)
+	switch v := v.(type) {
+	case int64:
+		if bits < 64 {
+			return v << (64 - bits) >> (64 - bits)
+		}
+	case uint64:
+		if bits < 64 {
+			return v & (1<<bits - 1)
+		}
+	case float64:
+		if basic(t).Kind() == types.Float32 {
+			return float64(float32(v))
+		}
+	}
+	return v
+}
+
+// convert implements the conversion T(v) from type from to type to.
+func (in *interp) convert(v value, from, to types.Type) (value, error) {
+	tb, fb := basic(to), basic(from)
+	if tb == nil || fb == nil {
+		if types.IdenticalIgnoreTags(from.Underlying(), to.Underlying()) {
+			return copyVal(v), nil
+		}
+		return nil, fmt.Errorf("conversion from %s to %s", from, to)
+	}
+	switch {
+	case tb.Info()&types.IsString != 0:
+		switch v := v.(type) {
+		case string:
+			return v, nil
+		case int64:
+			return string(rune(v)), nil
+		case uint64:
+			return string(rune(v)), nil
+		}
+	case tb.Info()&types.IsUnsigned != 0:
+		switch v := v.(type) {
+		case int64:
+			return in.wrap(uint64(v), to), nil
+		case uint64:
+			return in.wrap(v, to), nil
+		case float64:
+			return in.wrap(uint64(v), to), nil
+		}
+	case tb.Info()&types.IsInteger != 0:
+		switch v := v.(type) {
+		case int64:
+			return in.wrap(v, to), nil
+		case uint64:
+			return in.wrap(int64(v), to), nil
+		case float64:
+			return in.wrap(int64(v), to), nil
+		}
+	case tb.Info()&types.IsFloat != 0:
+		switch v := v.(type) {
+		case int64:
+			return in.wrap(float64(v), to), nil
+		case uint64:
+			return in.wrap(float64(v), to), nil
+		case float64:
+			return in.wrap(v, to), nil
+		}
+	case tb.Info()&types.IsBoolean != 0:
+		return v, nil
+	}
+	return nil, fmt.Errorf("conversion from %s to %s", from, to)
+}
+
+// format renders v the way fmt's %v verb would. Pointers print their
+// simulated address, except that a pointer to a struct at top level
+// prints as &{...}.
+func (in *interp) format(v value, t types.Type, top bool) string {
+	switch v := v.(type) {
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		bits := 64
+		if basic(t).Kind() == types.Float32 {
+			bits = 32
+		}
+		return strconv.FormatFloat(v, 'g', -1, bits)
+	case string:
+		return v
+	case structVal:
+		st := t.Underlying().(*types.Struct)
+		parts := make([]string, len(v))
+		for i, f := range v {
+			parts[i] = in.format(f, st.Field(i).Type(), false)
+		}
+		return "{" + strings.Join(parts, " ") + "}"
+	case pointer:
+		if v.isNil() {
+			return "<nil>"
+		}
+		elem := t.Underlying().(*types.Pointer).Elem()
+		if _, ok := elem.Underlying().(*types.Struct); ok && top {
+			return "&" + in.format(in.load(v), elem, false)
+		}
+		return hex(in.mem.addr(v.v, v.path))
+	case *closure:
+		if v == nil {
+			return "<nil>"
+		}
+		return hex(in.codeAddr(v.node))
+	}
+	return fmt.Sprint(v)
+}
+
+// describe renders v for memory-flow diagrams: like format, but strings
+// are quoted and functions show their signature.
+func (in *interp) describe(v value, t types.Type) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case structVal:
+		st := t.Underlying().(*types.Struct)
+		parts := make([]string, len(v))
+		for i, f := range v {
+			parts[i] = in.describe(f, st.Field(i).Type())
+		}
+		return "{" + strings.Join(parts, " ") + "}"
+	case pointer:
+		if v.isNil() {
+			return "nil"
+		}
+		return hex(in.mem.addr(v.v, v.path))
+	case *closure:
+		if v == nil {
+			return "nil"
+		}
+		return "func" + strings.TrimPrefix(types.TypeString(v.sig, in.qualifier), "func")
+	}
+	return in.format(v, t, false)
+}
+
+// printArg renders v, of type t, the way the println builtin does.
+func (in *interp) printArg(v value, t types.Type) (string, error) {
+	switch v := v.(type) {
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		if in.oldFloats {
+			return printFloat(v), nil
+		}
+		bits := 64
+		if b, ok := t.Underlying().(*types.Basic); ok && b.Kind() == types.Float32 {
+			bits = 32
+		}
+		return strconv.FormatFloat(v, 'g', -1, bits), nil
+	case string:
+		return v, nil
+	case pointer:
+		if v.isNil() {
+			return "0x0", nil
+		}
+		return hex(in.mem.addr(v.v, v.path)), nil
+	case *closure:
+		if v == nil {
+			return "0x0", nil
+		}
+		return hex(in.codeAddr(v.node)), nil
+	}
+	return "", fmt.Errorf("illegal type for println: %T", v)
+}
+
+// printFloat reproduces the runtime's float printing before Go 1.26:
+// seven significant digits and a three-digit exponent, e.g.
+// +3.140000e+000. Since Go 1.26 println prints the shortest
+// representation, as strconv.FormatFloat(v, 'g', -1, 64) does: 3.14.
+func printFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	const n = 7
+	var buf [n + 7]byte
+	buf[0] = '+'
+	e := 0
+	if v == 0 {
+		if math.Signbit(v) {
+			buf[0] = '-'
+		}
+	} else {
+		if v < 0 {
+			v = -v
+			buf[0] = '-'
+		}
+		for v >= 10 {
+			e++
+			v /= 10
+		}
+		for v < 1 {
+			e--
+			v *= 10
+		}
+		h := 5.0
+		for i := 0; i < n; i++ {
+			h /= 10
+		}
+		v += h
+		if v >= 10 {
+			e++
+			v /= 10
+		}
+	}
+	for i := 0; i < n; i++ {
+		s := int(v)
+		buf[i+2] = byte(s + '0')
+		v -= float64(s)
+		v *= 10
+	}
+	buf[1] = buf[2]
+	buf[2] = '.'
+	buf[n+2] = 'e'
+	buf[n+3] = '+'
+	if e < 0 {
+		e = -e
+		buf[n+3] = '-'
+	}
+	buf[n+4] = byte(e/100 + '0')
+	buf[n+5] = byte(e/10)%10 + '0'
+	buf[n+6] = byte(e%10) + '0'
+	return string(buf[:])
+}
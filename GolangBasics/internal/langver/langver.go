@@ -0,0 +1,360 @@
+// Package langver works out the oldest Go version each lesson compiles
+// with. Language features are found by type-checking the package with
+// types.Config.GoVersion set to older versions and keeping the errors the
+// type checker reports inside the lesson and the functions it calls
+// ("requires go1.22 or later").
+// Standard library API is looked up in the $GOROOT/api files, which record
+// the release that added each exported name.
+package langver
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"go/version"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"basics/internal/lesson"
+)
+
+// Problem is one thing that stops a lesson compiling at an older version.
+type Problem struct {
+	Pos     token.Position
+	Message string // the type checker's error, or the API requirement
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Pos, p.Message)
+}
+
+// Report is the version analysis of one lesson.
+type Report struct {
+	Lesson lesson.Lesson
+	Min    string // oldest version that compiles the lesson
+	Steps  []Step // newest first
+}
+
+// Step lists the problems that first appear when compiling at Version.
+type Step struct {
+	Version string
+	Errors  []Problem // reported by the type checker
+	API     []Problem // uses of standard library names added after Version
+}
+
+// Analyze reports the minimum Go version of each lesson in dir, checking
+// versions up to and including upTo.
+func Analyze(dir string, lessons []lesson.Lesson, upTo string) ([]Report, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	funcs := make(map[string]*ast.FuncDecl)
+	for _, f := range files {
+		for _, d := range f.Decls {
+			if fd, ok := d.(*ast.FuncDecl); ok && fd.Recv == nil {
+				funcs[fd.Name.Name] = fd
+			}
+		}
+	}
+	api, err := loadAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	// errs[m] are the type errors of the package at version go1.m.
+	imp := importer.ForCompiler(fset, "gc", nil)
+	maxMinor := minor(upTo)
+	errs := make(map[int][]types.Error)
+	var info *types.Info
+	for m := maxMinor; m >= 0; m-- {
+		conf := types.Config{
+			GoVersion: goVersion(m),
+			Importer:  imp,
+			Error:     func(err error) { errs[m] = append(errs[m], err.(types.Error)) },
+		}
+		in := &types.Info{
+			Defs: make(map[*ast.Ident]types.Object),
+			Uses: make(map[*ast.Ident]types.Object),
+		}
+		conf.Check(bp.ImportPath, fset, files, in)
+		if m == maxMinor {
+			info = in
+		}
+	}
+
+	// problems[m][i] are the problems in lessons[i], and the functions it
+	// calls, at version go1.m.
+	decls := make([][]*ast.FuncDecl, len(lessons))
+	for i, l := range lessons {
+		fd := funcs[l.Func]
+		if fd == nil {
+			return nil, fmt.Errorf("lesson %s: no function %s", l.Name, l.Func)
+		}
+		decls[i] = reachable(fd, files, info)
+	}
+	problems := make(map[int][][]Problem)
+	for m := maxMinor; m >= 0; m-- {
+		byLesson := make([][]Problem, len(lessons))
+		for i := range lessons {
+			for _, e := range errs[m] {
+				for _, fd := range decls[i] {
+					if e.Pos >= fd.Pos() && e.Pos < fd.End() {
+						byLesson[i] = append(byLesson[i], Problem{Pos: position(fset, e.Pos), Message: e.Msg})
+					}
+				}
+			}
+		}
+		problems[m] = byLesson
+	}
+	if errs := problems[maxMinor]; errs != nil {
+		for i, p := range errs {
+			if len(p) > 0 {
+				return nil, fmt.Errorf("lesson %s does not compile at %s: %v", lessons[i].Name, upTo, p[0])
+			}
+		}
+	}
+
+	var reports []Report
+	for i, l := range lessons {
+		var uses []apiUse
+		seenAPI := make(map[string]bool)
+		for _, fd := range decls[i] {
+			uses = append(uses, apiUses(fset, fd, info, api, seenAPI)...)
+		}
+		need := 0
+		for m := maxMinor; m >= 0; m-- {
+			if len(problems[m][i]) > 0 {
+				need = m + 1
+				break
+			}
+		}
+		for _, u := range uses {
+			need = max(need, u.minor)
+		}
+		r := Report{Lesson: l, Min: goVersion(need)}
+		seen := make(map[token.Position]bool)
+		for m := need - 1; m >= 0; m-- {
+			var st Step
+			for _, p := range problems[m][i] {
+				if !seen[p.Pos] {
+					seen[p.Pos] = true
+					st.Errors = append(st.Errors, p)
+				}
+			}
+			for _, u := range uses {
+				if u.minor == m+1 {
+					st.API = append(st.API, Problem{Pos: u.pos, Message: fmt.Sprintf("%s requires %s", u.name, goVersion(u.minor))})
+				}
+			}
+			if len(st.Errors) == 0 && len(st.API) == 0 {
+				continue
+			}
+			byString := func(a, b Problem) int {
+				return strings.Compare(a.String(), b.String())
+			}
+			slices.SortFunc(st.Errors, byString)
+			slices.SortFunc(st.API, byString)
+			st.Version = goVersion(m)
+			r.Steps = append(r.Steps, st)
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// reachable returns fd and the functions and methods of the package that
+// it calls, directly or indirectly, so a lesson's helpers count towards its
+// version.
+func reachable(fd *ast.FuncDecl, files []*ast.File, info *types.Info) []*ast.FuncDecl {
+	byObj := make(map[types.Object]*ast.FuncDecl)
+	for _, f := range files {
+		for _, d := range f.Decls {
+			if d, ok := d.(*ast.FuncDecl); ok {
+				byObj[info.Defs[d.Name]] = d
+			}
+		}
+	}
+	seen := map[*ast.FuncDecl]bool{fd: true}
+	list := []*ast.FuncDecl{fd}
+	for i := 0; i < len(list); i++ {
+		ast.Inspect(list[i], func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			if d := byObj[info.Uses[id]]; d != nil && !seen[d] {
+				seen[d] = true
+				list = append(list, d)
+			}
+			return true
+		})
+	}
+	return list
+}
+
+func position(fset *token.FileSet, pos token.Pos) token.Position {
+	p := fset.Position(pos)
+	p.Filename = filepath.Base(p.Filename)
+	return p
+}
+
+// goVersion returns the language version go1.m; go1.0 is spelled "go1".
+func goVersion(m int) string {
+	if m == 0 {
+		return "go1"
+	}
+	return "go1." + strconv.Itoa(m)
+}
+
+func minor(v string) int {
+	lang := version.Lang(v)
+	m, _ := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(lang, "go1"), "."))
+	return m
+}
+
+// apiUse is a use of a standard library name added after Go 1.0.
+type apiUse struct {
+	name  string
+	pos   token.Position
+	minor int
+}
+
+// apiUses returns the uses in fd of names not yet in seen, and adds them
+// to seen.
+func apiUses(fset *token.FileSet, fd *ast.FuncDecl, info *types.Info, api map[string]int, seen map[string]bool) []apiUse {
+	var uses []apiUse
+	ast.Inspect(fd, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		key, name := apiKey(info.Uses[id])
+		if m := api[key]; m > 0 && !seen[key] {
+			seen[key] = true
+			uses = append(uses, apiUse{name: name, pos: position(fset, id.Pos()), minor: m})
+		}
+		return true
+	})
+	return uses
+}
+
+// apiKey returns the key of obj in the API table, and its display name.
+func apiKey(obj types.Object) (key, name string) {
+	if obj == nil || obj.Pkg() == nil {
+		return "", ""
+	}
+	path := obj.Pkg().Path()
+	if fn, ok := obj.(*types.Func); ok {
+		if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+			t := recv.Type()
+			if p, ok := t.(*types.Pointer); ok {
+				t = p.Elem()
+			}
+			if n, ok := t.(*types.Named); ok {
+				return path + "." + n.Obj().Name() + "." + fn.Name(), obj.Pkg().Name() + "." + n.Obj().Name() + "." + fn.Name()
+			}
+			return "", ""
+		}
+	}
+	if obj.Parent() != obj.Pkg().Scope() {
+		return "", ""
+	}
+	return path + "." + obj.Name(), obj.Pkg().Name() + "." + obj.Name()
+}
+
+// loadAPI reads $GOROOT/api/go1.N.txt into a map from "path.Name" or
+// "path.Type.Method" to N. The Go 1.0 API is in go1.txt and maps to 0:
+// without it, names that a later release added for another port, such as
+// os.OpenFile, would look as new as that release.
+func loadAPI() (map[string]int, error) {
+	out, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		return nil, fmt.Errorf("go env GOROOT: %v", err)
+	}
+	dir := filepath.Join(strings.TrimSpace(string(out)), "api")
+	names, err := filepath.Glob(filepath.Join(dir, "go1.*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no API files in %s", dir)
+	}
+	api := make(map[string]int)
+	if err := readAPI(filepath.Join(dir, "go1.txt"), 0, api); err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		m, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(name), "go1."), ".txt"))
+		if err != nil {
+			continue
+		}
+		if err := readAPI(name, m, api); err != nil {
+			return nil, err
+		}
+	}
+	return api, nil
+}
+
+// readAPI parses lines such as
+//
+//	pkg structs, type HostLayout struct #66408
+//	pkg go/types, method (*Var) Origin() *Var
+func readAPI(file string, m int, api map[string]int) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line, ok := strings.CutPrefix(sc.Text(), "pkg ")
+		if !ok {
+			continue
+		}
+		path, rest, ok := strings.Cut(line, ", ")
+		if !ok {
+			continue
+		}
+		path, _, _ = strings.Cut(path, " ") // drop "(linux-386)"
+		kind, rest, _ := strings.Cut(rest, " ")
+		var key string
+		switch kind {
+		case "func", "type", "var", "const":
+			key = path + "." + strings.FieldsFunc(rest, func(r rune) bool { return r == ' ' || r == '(' || r == '[' || r == ',' })[0]
+		case "method":
+			recv, name, ok := strings.Cut(strings.TrimPrefix(rest, "("), ") ")
+			if !ok {
+				continue
+			}
+			recv = strings.TrimPrefix(recv, "*")
+			recv, _, _ = strings.Cut(recv, "[")
+			name, _, _ = strings.Cut(name, "(")
+			key = path + "." + recv + "." + name
+		default:
+			continue
+		}
+		if old, ok := api[key]; !ok || m < old {
+			api[key] = m
+		}
+	}
+	return sc.Err()
+}
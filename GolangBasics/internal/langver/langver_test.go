@@ -0,0 +1,80 @@
+package langver
+
+import (
+	"strings"
+	"testing"
+
+	"basics/internal/lesson"
+)
+
+func TestLoadAPI(t *testing.T) {
+	api, err := loadAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]int{
+		"os.OpenFile":                    0, // in go1.txt, and again in go1.16.txt for a new port
+		"time.Millisecond":               0,
+		"text/tabwriter.AlignRight":      0,
+		"math/big.Rat.Float64":           1,
+		"errors.Is":                      13,
+		"sync/atomic.Int64.Add":          19,
+		"structs.HostLayout":             23,
+		"testing.BenchmarkResult.String": 0,
+	} {
+		if got, ok := api[key]; !ok || got != want {
+			t.Errorf("api[%q] = %d, %v; want %d", key, got, ok, want)
+		}
+	}
+}
+
+// TestAnalyze type-checks the module's lessons at older versions.
+func TestAnalyze(t *testing.T) {
+	const dir = "../.."
+	lessons, err := lesson.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var picked []lesson.Lesson
+	for _, l := range lessons {
+		if l.Name == "sorting" || l.Name == "pointer" {
+			picked = append(picked, l)
+		}
+	}
+	reports, err := Analyze(dir, picked, "go1.23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]Report)
+	for _, r := range reports {
+		got[r.Lesson.Name] = r
+	}
+
+	if r := got["pointer"]; r.Min != "go1" || len(r.Steps) != 0 {
+		t.Errorf("pointer: needs %s with %d steps, want go1 and none", r.Min, len(r.Steps))
+	}
+
+	r := got["sorting"]
+	if r.Min != "go1.22" {
+		t.Errorf("sorting needs %s, want go1.22", r.Min)
+	}
+	if len(r.Steps) == 0 || r.Steps[0].Version != "go1.21" {
+		t.Fatalf("sorting: first step %v, want go1.21", r.Steps)
+	}
+	st := r.Steps[0]
+	if len(st.Errors) != 1 || !strings.Contains(st.Errors[0].Message, "requires go1.22 or later") {
+		t.Errorf("sorting at go1.21: type errors %v, want one range-over-int error", st.Errors)
+	}
+	if len(st.API) != 1 || st.API[0].Message != "cmp.Or requires go1.22" {
+		t.Errorf("sorting at go1.21: API %v, want cmp.Or", st.API)
+	}
+	for _, st := range r.Steps {
+		if st.Version == "go1" {
+			for _, p := range st.API {
+				if strings.Contains(p.Message, "tabwriter.AlignRight") {
+					t.Errorf("tabwriter.AlignRight is Go 1.0 API, got %v", p)
+				}
+			}
+		}
+	}
+}
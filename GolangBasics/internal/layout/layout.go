@@ -0,0 +1,277 @@
+// Package layout computes and explains the memory layout of struct types
+// the way the gc compiler lays them out: field offsets, alignment, padding
+// holes, and the special cases of zero-size fields.
+package layout
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Field is the layout of one struct field.
+type Field struct {
+	Name    string
+	Type    string
+	Offset  int64
+	Size    int64
+	Align   int64
+	Padding int64 // padding bytes inserted before the field
+}
+
+// Struct is the layout of one struct type.
+type Struct struct {
+	Name     string // type name; local types are qualified by their function
+	Pos      token.Position
+	Size     int64
+	Align    int64
+	Fields   []Field
+	Trailing int64 // padding bytes after the last field
+	Optimal  int64 // size with fields sorted by decreasing alignment
+	Notes    []string
+}
+
+// Of computes the layout of st under sizes.
+func Of(name string, st *types.Struct, sizes types.Sizes) Struct {
+	s := Struct{Name: name, Size: sizes.Sizeof(st), Align: sizes.Alignof(st)}
+	vars := make([]*types.Var, st.NumFields())
+	for i := range vars {
+		vars[i] = st.Field(i)
+	}
+	offsets := sizes.Offsetsof(vars)
+	end := int64(0)
+	for i, v := range vars {
+		f := Field{
+			Name:    v.Name(),
+			Type:    types.TypeString(v.Type(), shortQualifier),
+			Offset:  offsets[i],
+			Size:    sizes.Sizeof(v.Type()),
+			Align:   sizes.Alignof(v.Type()),
+			Padding: offsets[i] - end,
+		}
+		end = f.Offset + f.Size
+		s.Fields = append(s.Fields, f)
+	}
+	s.Trailing = s.Size - end
+	s.Optimal = optimal(vars, sizes)
+	s.Notes = explain(s, vars)
+	return s
+}
+
+// optimal returns the size of the struct with its fields sorted by
+// decreasing alignment, zero-size fields first so none is trailing.
+func optimal(vars []*types.Var, sizes types.Sizes) int64 {
+	sorted := append([]*types.Var(nil), vars...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		zi, zj := sizes.Sizeof(sorted[i].Type()) == 0, sizes.Sizeof(sorted[j].Type()) == 0
+		if zi != zj {
+			return zi
+		}
+		return sizes.Alignof(sorted[i].Type()) > sizes.Alignof(sorted[j].Type())
+	})
+	return sizes.Sizeof(types.NewStruct(sorted, nil))
+}
+
+// explain describes the parts of the layout that are not obvious from
+// the field table.
+func explain(s Struct, vars []*types.Var) []string {
+	var notes []string
+	if s.Size == 0 {
+		notes = append(notes, "zero-size type: distinct values may share one address, so comparing their addresses is unspecified")
+	}
+	for i, f := range s.Fields {
+		host := isHostLayout(vars[i].Type())
+		if host {
+			notes = append(notes, fmt.Sprintf("field %s is structs.HostLayout: the layout must follow the host C ABI; the marker itself takes no space", f.Name))
+		}
+		if f.Padding > 0 {
+			notes = append(notes, fmt.Sprintf("%d byte(s) of padding before %s to reach %d-byte alignment", f.Padding, f.Name, f.Align))
+		}
+		if f.Size == 0 && s.Size > 0 {
+			// A trailing marker still costs padding like any zero-size field
+			if i == len(s.Fields)-1 {
+				notes = append(notes, fmt.Sprintf("trailing zero-size field %s adds %d byte(s) of padding so that &x.%s does not point past the end of x", f.Name, s.Trailing, f.Name))
+			} else if !host {
+				notes = append(notes, fmt.Sprintf("zero-size field %s takes no space", f.Name))
+			}
+		}
+	}
+	if n := len(s.Fields); s.Trailing > 0 && s.Fields[n-1].Size > 0 {
+		notes = append(notes, fmt.Sprintf("%d byte(s) of trailing padding to round the size up to a multiple of %d", s.Trailing, s.Align))
+	}
+	if s.Optimal < s.Size {
+		notes = append(notes, fmt.Sprintf("ordering fields by decreasing alignment would shrink the struct from %d to %d bytes", s.Size, s.Optimal))
+	}
+	return notes
+}
+
+func isHostLayout(t types.Type) bool {
+	n, ok := t.(*types.Named)
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == "structs" && n.Obj().Name() == "HostLayout"
+}
+
+func shortQualifier(p *types.Package) string {
+	return p.Name()
+}
+
+// Analyze type-checks the package in dir and returns the layout of every
+// named struct type declared in it, including types local to functions,
+// for the given GOARCH.
+func Analyze(dir, goarch string) ([]Struct, error) {
+	sizes := types.SizesFor("gc", goarch)
+	if sizes == nil {
+		return nil, fmt.Errorf("unknown GOARCH %q", goarch)
+	}
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	info := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "gc", nil), Sizes: sizes}
+	if _, err := conf.Check(bp.ImportPath, fset, files, info); err != nil {
+		return nil, err
+	}
+	var structs []Struct
+	for _, d := range declared(files, info) {
+		s := Of(d.name, d.st, sizes)
+		s.Pos = position(fset, d.obj.Pos())
+		structs = append(structs, s)
+	}
+	return structs, nil
+}
+
+// decl is a named struct type declared in a package.
+type decl struct {
+	name string // qualified by its function if local
+	obj  *types.TypeName
+	st   *types.Struct
+}
+
+// declared returns the named struct types declared in files, in source
+// order.
+func declared(files []*ast.File, info *types.Info) []decl {
+	var decls []decl
+	for _, f := range files {
+		var fn string
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				fn = n.Name.Name
+			case *ast.TypeSpec:
+				obj, ok := info.Defs[n.Name].(*types.TypeName)
+				if !ok {
+					return true
+				}
+				st, ok := obj.Type().Underlying().(*types.Struct)
+				if !ok {
+					return true
+				}
+				name := n.Name.Name
+				if obj.Parent() != obj.Pkg().Scope() {
+					name = fn + "." + name
+				}
+				decls = append(decls, decl{name: name, obj: obj, st: st})
+			}
+			return true
+		})
+	}
+	return decls
+}
+
+func position(fset *token.FileSet, pos token.Pos) token.Position {
+	p := fset.Position(pos)
+	p.Filename = filepath.Base(p.Filename)
+	return p
+}
+
+// Write prints the layout of s as a table followed by its notes.
+func (s Struct) Write(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%s): size %d, align %d\n", s.Name, s.Pos, s.Size, s.Align)
+	if len(s.Fields) > 0 {
+		tw := tabwriter.NewWriter(&b, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "  Offset\tField\tType\tSize\tAlign")
+		for _, f := range s.Fields {
+			if f.Padding > 0 {
+				fmt.Fprintf(tw, "  %d\t(padding)\t\t%d\n", f.Offset-f.Padding, f.Padding)
+			}
+			fmt.Fprintf(tw, "  %d\t%s\t%s\t%d\t%d\n", f.Offset, f.Name, f.Type, f.Size, f.Align)
+		}
+		if s.Trailing > 0 {
+			fmt.Fprintf(tw, "  %d\t(padding)\t\t%d\n", s.Size-s.Trailing, s.Trailing)
+		}
+		tw.Flush()
+	}
+	for _, n := range s.Notes {
+		fmt.Fprintf(&b, "  - %s\n", n)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Compare describes how the layout of a struct changed from old to new:
+// its size, alignment, and the offset, size or type of each field. Fields
+// are matched by name; blank fields by position. It returns nil if the
+// layouts are the same.
+func Compare(old, new Struct) []string {
+	var changes []string
+	if old.Size != new.Size {
+		changes = append(changes, fmt.Sprintf("size %d → %d", old.Size, new.Size))
+	}
+	if old.Align != new.Align {
+		changes = append(changes, fmt.Sprintf("align %d → %d", old.Align, new.Align))
+	}
+	key := func(i int, f Field) string {
+		if f.Name == "_" {
+			return fmt.Sprintf("_#%d", i)
+		}
+		return f.Name
+	}
+	oldFields := make(map[string]Field)
+	for i, f := range old.Fields {
+		oldFields[key(i, f)] = f
+	}
+	seen := make(map[string]bool)
+	for i, f := range new.Fields {
+		k := key(i, f)
+		seen[k] = true
+		o, ok := oldFields[k]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("field %s added at offset %d", f.Name, f.Offset))
+			continue
+		case o.Type != f.Type:
+			changes = append(changes, fmt.Sprintf("field %s type %s → %s", f.Name, o.Type, f.Type))
+		}
+		if o.Offset != f.Offset {
+			changes = append(changes, fmt.Sprintf("field %s offset %d → %d", f.Name, o.Offset, f.Offset))
+		}
+		if o.Size != f.Size && o.Type == f.Type {
+			changes = append(changes, fmt.Sprintf("field %s size %d → %d", f.Name, o.Size, f.Size))
+		}
+	}
+	for i, f := range old.Fields {
+		if !seen[key(i, f)] {
+			changes = append(changes, fmt.Sprintf("field %s removed (was at offset %d)", f.Name, f.Offset))
+		}
+	}
+	return changes
+}
@@ -0,0 +1,30 @@
+package layout
+
+import (
+	"go/importer"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+func TestTrailingHostLayout(t *testing.T) {
+	pkg, err := importer.Default().Import("structs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := pkg.Scope().Lookup("HostLayout").Type()
+	st := types.NewStruct([]*types.Var{
+		types.NewField(0, nil, "n", types.Typ[types.Int64], false),
+		types.NewField(0, nil, "h", host, false),
+	}, nil)
+	s := Of("t", st, types.SizesFor("gc", "amd64"))
+	if s.Size != 16 || s.Trailing != 8 {
+		t.Fatalf("size %d, trailing %d; want 16 and 8", s.Size, s.Trailing)
+	}
+	notes := strings.Join(s.Notes, "\n")
+	for _, want := range []string{"field h is structs.HostLayout", "trailing zero-size field h adds 8 byte(s) of padding"} {
+		if !strings.Contains(notes, want) {
+			t.Errorf("notes lack %q:\n%s", want, notes)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package layout
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"slices"
+
+	"basics/internal/modload"
+)
+
+// Sensitive uses of a struct type. A layout change to such a type is more
+// than a change in memory use: it changes bytes on the wire, breaks
+// pointer arithmetic, or misaligns memory shared with other code.
+const (
+	UseBinary = "encoding/binary" // passed to encoding/binary
+	UseUnsafe = "unsafe"          // measured with unsafe.Sizeof/Offsetof/Alignof or converted to unsafe.Pointer
+	UseShared = "shared memory"   // raw memory viewed through it, or its fields used with sync/atomic
+)
+
+// ModuleStruct is the layout of a struct type in one package of a module.
+type ModuleStruct struct {
+	Struct
+	Package string   // import path of the declaring package
+	Uses    []string // sensitive uses anywhere in the module, sorted
+}
+
+// Key identifies the struct across revisions of the module.
+func (s ModuleStruct) Key() string {
+	return s.Package + "." + s.Name
+}
+
+// AnalyzeModule type-checks every package of the module in dir once and
+// returns the layouts of their named struct types for each of goarchs,
+// keyed by GOARCH. Files are selected by the build constraints of the
+// host platform; only the sizes and alignments differ per GOARCH.
+func AnalyzeModule(ctx context.Context, dir string, goarchs []string) (map[string][]ModuleStruct, error) {
+	all := make(map[string]types.Sizes)
+	for _, arch := range goarchs {
+		if all[arch] = types.SizesFor("gc", arch); all[arch] == nil {
+			return nil, fmt.Errorf("unknown GOARCH %q", arch)
+		}
+	}
+	pkgs, err := modload.Load(ctx, dir, "./...")
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[*types.TypeName]string)
+	for _, p := range pkgs {
+		for _, d := range declared(p.Files, p.Info) {
+			names[d.obj] = d.name
+		}
+	}
+	uses := sensitiveUses(pkgs, names)
+
+	structs := make(map[string][]ModuleStruct)
+	for _, p := range pkgs {
+		for _, d := range declared(p.Files, p.Info) {
+			for _, arch := range goarchs {
+				s := ModuleStruct{Struct: Of(d.name, d.st, all[arch]), Package: p.ImportPath}
+				s.Pos = position(p.Fset, d.obj.Pos())
+				s.Uses = uses[s.Key()]
+				structs[arch] = append(structs[arch], s)
+			}
+		}
+	}
+	return structs, nil
+}
+
+// sensitiveUses finds the struct types used in the ways listed by the Use
+// constants, keyed like ModuleStruct.Key. A use of a type covers the
+// struct types it is built from: writing a []T with encoding/binary
+// encodes every field of T.
+func sensitiveUses(pkgs []*modload.Loaded, names map[*types.TypeName]string) map[string][]string {
+	uses := make(map[string][]string)
+	mark := func(t types.Type, use string) {
+		for _, obj := range structsOf(t) {
+			key := obj.Pkg().Path() + "." + obj.Name()
+			if name, ok := names[obj]; ok {
+				key = obj.Pkg().Path() + "." + name
+			}
+			if !slices.Contains(uses[key], use) {
+				uses[key] = append(uses[key], use)
+				slices.Sort(uses[key])
+			}
+		}
+	}
+	for _, p := range pkgs {
+		typeOf := func(e ast.Expr) types.Type { return p.Info.Types[e].Type }
+		for _, f := range p.Files {
+			ast.Inspect(f, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				if tv := p.Info.Types[call.Fun]; tv.IsType() && len(call.Args) == 1 {
+					// Conversions between unsafe.Pointer and a struct pointer.
+					to, from := tv.Type, typeOf(call.Args[0])
+					switch {
+					case isUnsafePointer(to) && from != nil:
+						mark(from, UseUnsafe)
+					case isUnsafePointer(from):
+						mark(to, UseShared)
+					}
+					return true
+				}
+				switch pkgFunc(p.Info, call.Fun) {
+				case "encoding/binary":
+					for _, a := range call.Args {
+						mark(typeOf(a), UseBinary)
+					}
+				case "unsafe":
+					for _, a := range call.Args {
+						if sel, ok := ast.Unparen(a).(*ast.SelectorExpr); ok {
+							mark(typeOf(sel.X), UseUnsafe) // Offsetof(x.f)
+						} else {
+							mark(typeOf(a), UseUnsafe)
+						}
+					}
+				case "sync/atomic":
+					for _, a := range call.Args {
+						u, ok := ast.Unparen(a).(*ast.UnaryExpr)
+						if !ok {
+							continue
+						}
+						if sel, ok := ast.Unparen(u.X).(*ast.SelectorExpr); ok {
+							mark(typeOf(sel.X), UseShared) // atomic.AddInt64(&x.n, 1)
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+	return uses
+}
+
+// pkgFunc returns the import path of the package whose function fun
+// names, as in binary.Write, or "".
+func pkgFunc(info *types.Info, fun ast.Expr) string {
+	sel, ok := ast.Unparen(fun).(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	id, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	if pn, ok := info.Uses[id].(*types.PkgName); ok {
+		return pn.Imported().Path()
+	}
+	return ""
+}
+
+func isUnsafePointer(t types.Type) bool {
+	b, ok := t.(*types.Basic)
+	return ok && b.Kind() == types.UnsafePointer
+}
+
+// structsOf returns the named struct types that make up t: t itself, the
+// elements of pointers, slices and arrays, and the types of struct
+// fields, recursively.
+func structsOf(t types.Type) []*types.TypeName {
+	var list []*types.TypeName
+	seen := make(map[types.Type]bool)
+	var walk func(t types.Type)
+	walk = func(t types.Type) {
+		if t == nil || seen[t] {
+			return
+		}
+		seen[t] = true
+		switch u := t.(type) {
+		case *types.Named:
+			if _, ok := u.Underlying().(*types.Struct); ok && u.Obj().Pkg() != nil {
+				list = append(list, u.Obj())
+			}
+			walk(u.Underlying())
+		case *types.Alias:
+			walk(types.Unalias(u))
+		case *types.Pointer:
+			walk(u.Elem())
+		case *types.Slice:
+			walk(u.Elem())
+		case *types.Array:
+			walk(u.Elem())
+		case *types.Struct:
+			for i := range u.NumFields() {
+				walk(u.Field(i).Type())
+			}
+		}
+	}
+	walk(t)
+	return list
+}
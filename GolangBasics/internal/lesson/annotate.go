@@ -0,0 +1,264 @@
+package lesson
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/version"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// RegistryFile is the generated file holding the lessons table.
+const RegistryFile = "lessons_gen.go"
+
+// A lesson is declared by a line in its function's doc comment:
+//
+//	// lesson: pointer section=pointers--reference-semantics
+//	func pointer() {
+//
+// with an optional goVersion=go1.23 after the section, and goos=linux
+// for a lesson that only builds on one operating system. Such a lesson
+// lives in a file like syscalls_linux.go, with a stub of its function for
+// the other systems so that the lessons table compiles everywhere. Scan
+// collects these annotations and Generate turns them into RegistryFile.
+const annotationPrefix = "// lesson:"
+
+// Scan returns the lessons annotated in the package in dir, in the order
+// their README sections appear, and checks that each lesson function
+// takes no arguments and returns nothing, that names are unique and that
+// every section exists in dir/README.md. Files excluded by build
+// constraints are scanned too, so the table is the same on every host.
+func Scan(dir string) ([]Lesson, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	anchors, err := Anchors(filepath.Join(dir, "README.md"))
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var lessons []Lesson
+	var pos []token.Position
+	var errs []error
+	names := append(slices.Clone(bp.GoFiles), bp.IgnoredGoFiles...)
+	slices.Sort(names)
+	for _, name := range names {
+		if name == RegistryFile {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		docs := make(map[*ast.CommentGroup]*ast.FuncDecl)
+		for _, d := range f.Decls {
+			if fd, ok := d.(*ast.FuncDecl); ok && fd.Doc != nil {
+				docs[fd.Doc] = fd
+			}
+		}
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				text, ok := strings.CutPrefix(c.Text, annotationPrefix)
+				if !ok {
+					continue
+				}
+				p := fset.Position(c.Pos())
+				p.Filename = name
+				fd := docs[cg]
+				if fd == nil {
+					errs = append(errs, fmt.Errorf("%s: lesson annotation is not in the doc comment of a function", p))
+					continue
+				}
+				l, err := parseAnnotation(text)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %v", p, err))
+					continue
+				}
+				if fd.Recv != nil || fd.Type.TypeParams != nil || fd.Type.Params.NumFields() > 0 || fd.Type.Results.NumFields() > 0 {
+					errs = append(errs, fmt.Errorf("%s: lesson %s: %s must be a function with no type parameters, parameters or results", p, l.Name, fd.Name.Name))
+					continue
+				}
+				if _, ok := anchors[l.Section]; !ok {
+					errs = append(errs, fmt.Errorf("%s: lesson %s: README.md has no section #%s", p, l.Name, l.Section))
+					continue
+				}
+				if i := slices.IndexFunc(lessons, func(o Lesson) bool { return o.Name == l.Name }); i >= 0 {
+					errs = append(errs, fmt.Errorf("%s: lesson %s is also declared at %s", p, l.Name, pos[i]))
+					continue
+				}
+				l.Func = fd.Name.Name
+				l.File = path
+				lessons = append(lessons, l)
+				pos = append(pos, p)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	order := make([]int, len(lessons))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(i, j int) int {
+		return anchors[lessons[i].Section] - anchors[lessons[j].Section]
+	})
+	sorted := make([]Lesson, len(lessons))
+	for i, o := range order {
+		sorted[i] = lessons[o]
+	}
+	return sorted, nil
+}
+
+// parseAnnotation parses the text after "// lesson:".
+func parseAnnotation(text string) (Lesson, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || strings.Contains(fields[0], "=") {
+		return Lesson{}, errors.New("lesson annotation has no name")
+	}
+	l := Lesson{Name: fields[0]}
+	for _, f := range fields[1:] {
+		key, val, ok := strings.Cut(f, "=")
+		if !ok {
+			return Lesson{}, fmt.Errorf("lesson %s: %q is not key=value", l.Name, f)
+		}
+		switch key {
+		case "section":
+			l.Section = strings.TrimPrefix(val, "#")
+		case "goVersion":
+			if !version.IsValid(val) {
+				return Lesson{}, fmt.Errorf("lesson %s: invalid goVersion %q", l.Name, val)
+			}
+			l.GoVersion = val
+		case "goos":
+			l.GOOS = val
+		default:
+			return Lesson{}, fmt.Errorf("lesson %s: unknown key %q", l.Name, key)
+		}
+	}
+	if l.Section == "" {
+		return Lesson{}, fmt.Errorf("lesson %s: no section=", l.Name)
+	}
+	return l, nil
+}
+
+// annotation returns the annotation line declaring l.
+func annotation(l Lesson) string {
+	s := fmt.Sprintf("%s %s section=%s", annotationPrefix, l.Name, l.Section)
+	if l.GoVersion != "" {
+		s += " goVersion=" + l.GoVersion
+	}
+	if l.GOOS != "" {
+		s += " goos=" + l.GOOS
+	}
+	return s
+}
+
+var headingRE = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*$`)
+
+// Anchors returns the anchors GitHub generates for the headings of the
+// Markdown file, mapped to their line numbers. Headings numbered like
+// "## 4. Type System" also get the anchor without the number
+// ("type-system"), the form the README's table of contents links to.
+func Anchors(file string) (map[string]int, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	anchors := make(map[string]int)
+	add := func(a string, line int) {
+		if _, ok := anchors[a]; !ok {
+			anchors[a] = line
+		}
+	}
+	seen := make(map[string]int)
+	fence := false
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		if strings.HasPrefix(strings.TrimSpace(text), "```") {
+			fence = !fence
+		}
+		m := headingRE.FindStringSubmatch(text)
+		if fence || m == nil {
+			continue
+		}
+		a := slug(m[1])
+		if n := seen[a]; n > 0 {
+			add(fmt.Sprintf("%s-%d", a, n), line)
+		} else {
+			add(a, line)
+		}
+		seen[a]++
+		if i := strings.Index(a, "-"); i > 0 && strings.TrimLeft(a[:i], "0123456789") == "" {
+			add(a[i+1:], line)
+		}
+	}
+	return anchors, sc.Err()
+}
+
+// slug lowercases a heading, drops punctuation and turns spaces into
+// hyphens, as GitHub does.
+func slug(heading string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case r == ' ':
+			b.WriteByte('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Generate returns the source of RegistryFile for lessons.
+func Generate(lessons []Lesson) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by lessongen from the \"// lesson:\" annotations. DO NOT EDIT.\n\n")
+	b.WriteString("package main\n\n")
+	b.WriteString("var lessons = []entry{\n")
+	for _, l := range lessons {
+		fmt.Fprintf(&b, "{name: %q, section: %q, ", l.Name, l.Section)
+		if l.GoVersion != "" {
+			fmt.Fprintf(&b, "goVersion: %q, ", l.GoVersion)
+		}
+		if l.GOOS != "" {
+			fmt.Fprintf(&b, "goos: %q, ", l.GOOS)
+		}
+		fmt.Fprintf(&b, "run: %s},\n", l.Func)
+	}
+	b.WriteString("}\n")
+	return format.Source(b.Bytes())
+}
+
+// WriteRegistry scans the package in dir and rewrites its RegistryFile if
+// the lessons changed. It reports whether the file was written.
+func WriteRegistry(dir string) (bool, error) {
+	lessons, err := Scan(dir)
+	if err != nil {
+		return false, err
+	}
+	src, err := Generate(lessons)
+	if err != nil {
+		return false, err
+	}
+	path := filepath.Join(dir, RegistryFile)
+	if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, src) {
+		return false, nil
+	}
+	return true, os.WriteFile(path, src, 0o644)
+}
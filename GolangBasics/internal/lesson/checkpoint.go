@@ -0,0 +1,153 @@
+package lesson
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// A Checkpoint is a print statement in a lesson function: a call of
+// fmt.Print, fmt.Println, fmt.Printf, print or println. The trainee
+// predicts what it prints before seeing it.
+type Checkpoint struct {
+	File string // base name of the file
+	Line int
+	Src  string // the statement as written
+}
+
+func (c Checkpoint) String() string {
+	return fmt.Sprintf("%s:%d", c.File, c.Line)
+}
+
+// checkpointMarker starts the line an instrumented lesson prints before
+// each checkpoint, followed by the checkpoint's index. println writes to
+// standard error, which Run merges with standard output in order.
+const checkpointMarker = "\x00checkpoint"
+
+var markerRE = regexp.MustCompile("(?m)^" + checkpointMarker + ` (\d+)\n`)
+
+// Instrument returns the source of the file declaring l with a marker
+// printed before each print statement in l's function, including those in
+// function literals inside it, and the checkpoints in marker order. The
+// markers are inserted on the statements' own lines, so line numbers in
+// the instrumented file, and in any panic it prints, are unchanged.
+func Instrument(l Lesson) ([]byte, []Checkpoint, error) {
+	src, err := os.ReadFile(l.File)
+	if err != nil {
+		return nil, nil, err
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, l.File, src, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	var fd *ast.FuncDecl
+	for _, d := range f.Decls {
+		if d, ok := d.(*ast.FuncDecl); ok && d.Recv == nil && d.Name.Name == l.Func {
+			fd = d
+		}
+	}
+	if fd == nil || fd.Body == nil {
+		return nil, nil, fmt.Errorf("%s: no function %s", l.File, l.Func)
+	}
+	// Only statements in statement lists: a marker cannot go before the
+	// post statement of a for loop.
+	var stmts []ast.Stmt
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		var list []ast.Stmt
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			list = n.List
+		case *ast.CaseClause:
+			list = n.Body
+		case *ast.CommClause:
+			list = n.Body
+		}
+		for _, s := range list {
+			for {
+				l, ok := s.(*ast.LabeledStmt)
+				if !ok {
+					break
+				}
+				s = l.Stmt
+			}
+			if e, ok := s.(*ast.ExprStmt); ok && isPrint(e.X) {
+				stmts = append(stmts, e)
+			}
+		}
+		return true
+	})
+	slices.SortFunc(stmts, func(a, b ast.Stmt) int { return int(a.Pos() - b.Pos()) })
+
+	var out bytes.Buffer
+	var cps []Checkpoint
+	last := 0
+	for i, s := range stmts {
+		start := fset.Position(s.Pos())
+		end := fset.Position(s.End())
+		cps = append(cps, Checkpoint{
+			File: filepath.Base(l.File),
+			Line: start.Line,
+			Src:  string(src[start.Offset:end.Offset]),
+		})
+		out.Write(src[last:start.Offset])
+		fmt.Fprintf(&out, "println(%q, %d); ", checkpointMarker, i)
+		last = start.Offset
+	}
+	out.Write(src[last:])
+	return out.Bytes(), cps, nil
+}
+
+// isPrint reports whether x calls one of the print functions, by name:
+// lesson files do not rename the fmt import or shadow println.
+func isPrint(x ast.Expr) bool {
+	call, ok := x.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	switch fn := call.Fun.(type) {
+	case *ast.Ident:
+		return fn.Name == "print" || fn.Name == "println"
+	case *ast.SelectorExpr:
+		pkg, ok := fn.X.(*ast.Ident)
+		return ok && pkg.Name == "fmt" && slices.Contains([]string{"Print", "Println", "Printf"}, fn.Sel.Name)
+	}
+	return false
+}
+
+// A Segment is the output of an instrumented lesson from one checkpoint's
+// marker to the next: what the print statement printed, plus anything
+// else printed before the next checkpoint. Checkpoint is -1 for the output
+// before the first marker.
+type Segment struct {
+	Checkpoint int
+	Output     []byte
+}
+
+// SplitCheckpoints splits the output of an instrumented lesson at its
+// markers.
+func SplitCheckpoints(out []byte) []Segment {
+	segs := []Segment{{Checkpoint: -1}}
+	for {
+		loc := markerRE.FindSubmatchIndex(out)
+		if loc == nil {
+			break
+		}
+		segs[len(segs)-1].Output = out[:loc[0]]
+		i, _ := strconv.Atoi(string(out[loc[2]:loc[3]]))
+		segs = append(segs, Segment{Checkpoint: i})
+		out = out[loc[1]:]
+	}
+	segs[len(segs)-1].Output = out
+	if len(segs[0].Output) == 0 {
+		segs = segs[1:]
+	}
+	return segs
+}
@@ -0,0 +1,117 @@
+package lesson
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const checkpointSrc = `package main
+
+import "fmt"
+
+func demo() {
+	x := 1
+	fmt.Println("x", x)
+	for i := range 2 {
+	loop:
+		println(i)
+		if i > 5 {
+			break loop
+		}
+	}
+	f := func() { fmt.Printf("%d\n", x) }
+	f()
+	switch x {
+	case 1:
+		fmt.Print("one\n")
+	}
+	fmt.Sprint("not printed")
+}
+
+func other() {
+	fmt.Println("not in demo")
+}
+`
+
+func TestInstrument(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "demo.go")
+	if err := os.WriteFile(file, []byte(checkpointSrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	src, cps, err := Instrument(Lesson{Name: "demo", Func: "demo", File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Checkpoint{
+		{"demo.go", 7, `fmt.Println("x", x)`},
+		{"demo.go", 10, "println(i)"},
+		{"demo.go", 15, `fmt.Printf("%d\n", x)`},
+		{"demo.go", 19, `fmt.Print("one\n")`},
+	}
+	if !slices.Equal(cps, want) {
+		t.Errorf("checkpoints\n%v\nwant\n%v", cps, want)
+	}
+
+	// Each marker goes on its statement's line, so the lines are unchanged
+	got := strings.Split(string(src), "\n")
+	orig := strings.Split(checkpointSrc, "\n")
+	if len(got) != len(orig) {
+		t.Fatalf("instrumented source has %d lines, want %d:\n%s", len(got), len(orig), src)
+	}
+	for i, c := range want {
+		line := got[c.Line-1]
+		marker := fmt.Sprintf(`println("\x00checkpoint", %d); `, i)
+		if !strings.Contains(line, marker+c.Src) {
+			t.Errorf("line %d is %q, want the marker %q before the statement", c.Line, line, marker)
+		}
+	}
+	if n := bytes.Count(src, []byte("checkpoint")); n != len(want) {
+		t.Errorf("%d markers, want %d", n, len(want))
+	}
+
+	if _, _, err := Instrument(Lesson{Name: "demo", Func: "missing", File: file}); err == nil {
+		t.Error("Instrument found a function that is not declared")
+	}
+}
+
+func TestSplitCheckpoints(t *testing.T) {
+	marker := func(i string) string { return checkpointMarker + " " + i + "\n" }
+	for _, tt := range []struct {
+		name string
+		out  string
+		want []Segment
+	}{
+		{"no markers", "hello\n", []Segment{{-1, []byte("hello\n")}}},
+		{"nothing before the first marker", marker("0") + "a\n" + marker("1") + "b\nc\n", []Segment{
+			{0, []byte("a\n")},
+			{1, []byte("b\nc\n")},
+		}},
+		{"output before the first marker", "setup\n" + marker("0") + "a\n", []Segment{
+			{-1, []byte("setup\n")},
+			{0, []byte("a\n")},
+		}},
+		{"a checkpoint printing nothing", marker("0") + marker("1") + "b\n", []Segment{
+			{0, []byte{}},
+			{1, []byte("b\n")},
+		}},
+		{"repeated checkpoint", marker("0") + "i 0\n" + marker("0") + "i 1\n", []Segment{
+			{0, []byte("i 0\n")},
+			{0, []byte("i 1\n")},
+		}},
+		{"a marker inside a line", "a " + marker("0"), []Segment{{-1, []byte("a " + marker("0"))}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitCheckpoints([]byte(tt.out))
+			if !slices.EqualFunc(got, tt.want, func(a, b Segment) bool {
+				return a.Checkpoint == b.Checkpoint && bytes.Equal(a.Output, b.Output)
+			}) {
+				t.Errorf("SplitCheckpoints(%q) = %q, want %q", tt.out, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package lesson
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+// CompileError is one error from a failed build of the lessons package.
+type CompileError struct {
+	Pos  string // file:line:col
+	Msg  string
+	Hint string // what the error usually means, or ""
+}
+
+func (e CompileError) String() string {
+	if e.Hint == "" {
+		return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s\n\t%s", e.Pos, e.Msg, e.Hint)
+}
+
+var errorRE = regexp.MustCompile(`^(\S+\.go:\d+:\d+): (.*)$`)
+
+// hints explain the compile errors people meet most while learning Go.
+// $1, $2... in a hint are replaced with the submatches of its pattern.
+var hints = []struct {
+	re   *regexp.Regexp
+	hint string
+}{
+	{regexp.MustCompile(`declared and not used: (\w+)`), "Go rejects unused local variables: use $1, delete it, or assign it to _"},
+	{regexp.MustCompile(`"([^"]+)" imported and not used`), `delete the import of "$1", or import it as _ if only its side effects are needed`},
+	{regexp.MustCompile(`no new variables on left side of :=`), "every variable on the left is already declared: assign with = instead of :="},
+	{regexp.MustCompile(`(\w+) redeclared in this block`), "$1 is declared twice in the same scope: use = to assign to the existing variable"},
+	{regexp.MustCompile(`undefined: (\S+)`), "$1 is not declared where it is used: check the spelling and scope, and that names used from other packages start with a capital letter"},
+	{regexp.MustCompile(`mismatched types (\S+) and (\S+)`), "both operands must have the same type; Go never converts implicitly, so convert one of them, e.g. $1(y)"},
+	{regexp.MustCompile(`cannot use .* as (\S+) value`), "Go never converts implicitly: write the conversion, e.g. $1(x), or change the declared type"},
+	{regexp.MustCompile(`missing return`), "every path through a function with results must end in a return statement"},
+	{regexp.MustCompile(`non-boolean condition`), "conditions must be bool: Go has no truthiness, so compare explicitly, e.g. n != 0 or p != nil"},
+	{regexp.MustCompile(`syntax error: unexpected newline`), "Go inserts a semicolon at the end of a line that could end a statement, so an opening { or a trailing operator must stay on the same line"},
+}
+
+// Errors returns the compile errors in the build output, each with a hint
+// when the message is a common one.
+func (e *BuildError) Errors() []CompileError {
+	var errs []CompileError
+	sc := bufio.NewScanner(bytes.NewReader(e.Output))
+	for sc.Scan() {
+		m := errorRE.FindStringSubmatch(sc.Text())
+		if m == nil {
+			continue
+		}
+		ce := CompileError{Pos: m[1], Msg: m[2]}
+		for _, h := range hints {
+			if sm := h.re.FindStringSubmatchIndex(ce.Msg); sm != nil {
+				ce.Hint = string(h.re.ExpandString(nil, h.hint, ce.Msg, sm))
+				break
+			}
+		}
+		errs = append(errs, ce)
+	}
+	return errs
+}
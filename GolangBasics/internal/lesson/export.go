@@ -0,0 +1,282 @@
+package lesson
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"basics/internal/txtar"
+)
+
+// An exported lesson is a txtar archive that the Go Playground and
+// "go run ." both accept:
+//
+//	-- go.mod --
+//	// lesson: devirt section=devirtualization--interface-call-cost goVersion=go1.1 run=devirt
+//	module devirt
+//
+//	go 1.23.3
+//	-- devirt.go --
+//	(the lesson function and every declaration of the package it uses)
+//	-- play.go --
+//	(a main function calling the lesson, unless the lesson is main)
+//	-- testdata/devirt.golden --
+//	(the expected output)
+//
+// The comment in go.mod is the lesson's annotation, plus the function to
+// run, for Import.
+
+// playFile is the generated file that runs a lesson other than main.
+const playFile = "play.go"
+
+// Export returns lesson l of the package in dir as a self-contained
+// archive.
+func Export(dir string, l Lesson) (*txtar.Archive, error) {
+	golden, err := os.ReadFile(GoldenPath(dir, l.Name))
+	if err != nil {
+		return nil, fmt.Errorf("lesson %s has no golden file: %v", l.Name, err)
+	}
+	modGo, err := ModuleGoVersion(dir)
+	if err != nil {
+		return nil, err
+	}
+	p, err := loadSource(dir)
+	if err != nil {
+		return nil, err
+	}
+	src, err := p.extract(l)
+	if err != nil {
+		return nil, err
+	}
+
+	var mod bytes.Buffer
+	fmt.Fprintf(&mod, "%s run=%s\nmodule %s\n\ngo %s\n", annotation(l), l.Func, l.Name, strings.TrimPrefix(modGo, "go"))
+	ar := &txtar.Archive{Files: []txtar.File{
+		{Name: "go.mod", Data: mod.Bytes()},
+		{Name: filepath.Base(l.File), Data: src},
+	}}
+	if l.Func != "main" {
+		play := fmt.Sprintf("package main\n\nfunc main() {\n\t%s()\n}\n", l.Func)
+		ar.Files = append(ar.Files, txtar.File{Name: playFile, Data: []byte(play)})
+	}
+	ar.Files = append(ar.Files, txtar.File{Name: "testdata/" + l.Name + ".golden", Data: golden})
+	return ar, nil
+}
+
+// source is the parsed and type-checked lessons package.
+type source struct {
+	fset  *token.FileSet
+	files []*ast.File
+	src   map[*ast.File][]byte
+	pkg   *types.Package
+	info  *types.Info
+}
+
+func loadSource(dir string) (*source, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	p := &source{fset: token.NewFileSet(), src: make(map[*ast.File][]byte)}
+	for _, name := range bp.GoFiles {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		f, err := parser.ParseFile(p.fset, filepath.Join(dir, name), data, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		p.files = append(p.files, f)
+		p.src[f] = data
+	}
+	p.info = &types.Info{
+		Defs: make(map[*ast.Ident]types.Object),
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{Importer: importer.ForCompiler(p.fset, "gc", nil)}
+	p.pkg, err = conf.Check(bp.ImportPath, p.fset, p.files, p.info)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// extract returns a source file holding the function of lesson l and the
+// declarations it uses, directly or indirectly, in the order they appear
+// in the package. Using a type brings in all its methods, since they may
+// be called through an interface.
+func (p *source) extract(l Lesson) ([]byte, error) {
+	decls := make(map[types.Object]ast.Decl)
+	methods := make(map[types.Object][]ast.Decl) // by receiver type name
+	fileOf := make(map[ast.Decl]*ast.File)
+	var lessonFile *ast.File
+	var root ast.Decl
+	for _, f := range p.files {
+		for _, d := range f.Decls {
+			fileOf[d] = f
+			switch d := d.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil {
+					decls[p.info.Defs[d.Name]] = d
+					if d.Name.Name == l.Func {
+						root, lessonFile = d, f
+					}
+					continue
+				}
+				sig := p.info.Defs[d.Name].Type().(*types.Signature)
+				if n, ok := deref(sig.Recv().Type()).(*types.Named); ok {
+					methods[n.Obj()] = append(methods[n.Obj()], d)
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						decls[p.info.Defs[spec.Name]] = d
+					case *ast.ValueSpec:
+						for _, id := range spec.Names {
+							decls[p.info.Defs[id]] = d
+						}
+					}
+				}
+			}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("lesson %s: no function %s", l.Name, l.Func)
+	}
+
+	used := map[ast.Decl]bool{root: true}
+	imports := make(map[*types.PkgName]bool)
+	queue := []ast.Decl{root}
+	add := func(d ast.Decl) {
+		if d != nil && !used[d] {
+			used[d] = true
+			queue = append(queue, d)
+		}
+	}
+	for len(queue) > 0 {
+		d := queue[0]
+		queue = queue[1:]
+		ast.Inspect(d, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			switch obj := p.info.Uses[id].(type) {
+			case *types.PkgName:
+				imports[obj] = true
+			case *types.TypeName:
+				add(decls[obj])
+				for _, m := range methods[obj] {
+					add(m)
+				}
+			case nil:
+			default:
+				add(decls[obj])
+			}
+			return true
+		})
+	}
+
+	var b bytes.Buffer
+	b.Write(p.src[lessonFile][:p.fset.Position(lessonFile.Package).Offset])
+	b.WriteString("package main\n\n")
+	var paths []string
+	for pn := range imports {
+		path := strconv.Quote(pn.Imported().Path())
+		if pn.Name() != pn.Imported().Name() {
+			path = pn.Name() + " " + path
+		}
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	paths = slices.Compact(paths)
+	if len(paths) > 0 {
+		b.WriteString("import (\n\t" + strings.Join(paths, "\n\t") + "\n)\n")
+	}
+	// The lesson's own file comes first, then the rest in package order.
+	files := append([]*ast.File{lessonFile}, slices.DeleteFunc(slices.Clone(p.files), func(f *ast.File) bool { return f == lessonFile })...)
+	for _, f := range files {
+		for _, d := range f.Decls {
+			if used[d] {
+				b.WriteString("\n")
+				b.Write(p.declText(f, d))
+				b.WriteString("\n")
+			}
+		}
+	}
+	return format.Source(b.Bytes())
+}
+
+// declText returns the source of d with its doc comment and any comment
+// after it on its last line. Lesson annotations are left out: go.mod
+// carries the lesson's entry instead.
+func (p *source) declText(f *ast.File, d ast.Decl) []byte {
+	start, end := d.Pos(), d.End()
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		if d.Doc != nil {
+			start = d.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if d.Doc != nil {
+			start = d.Doc.Pos()
+		}
+	}
+	endLine := p.fset.Position(end).Line
+	for _, c := range f.Comments {
+		if c.Pos() >= end && p.fset.Position(c.Pos()).Line == endLine {
+			end = c.End()
+		}
+	}
+	src := p.src[f]
+	return stripAnnotations(src[p.fset.Position(start).Offset:p.fset.Position(end).Offset])
+}
+
+func deref(t types.Type) types.Type {
+	if p, ok := t.(*types.Pointer); ok {
+		return p.Elem()
+	}
+	return t
+}
+
+// CopyPackage copies what Export and Import read of the lessons package
+// in dir to dst: go.mod, the README, the top-level .go files and the
+// golden files.
+func CopyPackage(dir, dst string) error {
+	var names []string
+	for _, pattern := range []string{"go.mod", "go.sum", "README.md", "*.go", "testdata/*.golden"} {
+		m, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return err
+		}
+		names = append(names, m...)
+	}
+	for _, name := range names {
+		rel, _ := filepath.Rel(dir, name)
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dst, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
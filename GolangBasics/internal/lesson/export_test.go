@@ -0,0 +1,57 @@
+package lesson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"basics/internal/txtar"
+)
+
+// TestExportImport exports each lesson of the module, reads the archive
+// back, imports it into a copy of the module under another name and
+// exports it again: every file but go.mod must come back unchanged.
+func TestExportImport(t *testing.T) {
+	const dir = "../.."
+	lessons, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range lessons {
+		t.Run(l.Name, func(t *testing.T) {
+			ar, err := Export(dir, l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data := txtar.Format(ar)
+			parsed := txtar.Parse(data)
+			if !bytes.Equal(txtar.Format(parsed), data) {
+				t.Fatal("archive changes when parsed and formatted again")
+			}
+
+			mod := t.TempDir()
+			if err := CopyPackage(dir, mod); err != nil {
+				t.Fatal(err)
+			}
+			copyName := l.Name + "_copy"
+			cl, err := Import(mod, parsed, copyName)
+			if err != nil {
+				t.Fatalf("import: %v", err)
+			}
+			again, err := Export(mod, cl)
+			if err != nil {
+				t.Fatalf("export after import: %v", err)
+			}
+			for _, f := range ar.Files {
+				name := strings.Replace(f.Name, "testdata/"+l.Name+".", "testdata/"+copyName+".", 1)
+				g := again.Lookup(name)
+				switch {
+				case g == nil:
+					t.Errorf("export after import has no %s", name)
+				case f.Name != "go.mod" && !bytes.Equal(f.Data, g.Data):
+					t.Errorf("%s changed after import and export:\n%s", f.Name, Diff(f.Data, g.Data))
+				}
+			}
+		})
+	}
+}
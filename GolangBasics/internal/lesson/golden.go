@@ -0,0 +1,89 @@
+package lesson
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// addrRE matches real memory addresses in lesson output. Short hex
+// numbers are left alone: lessons print those on purpose.
+var addrRE = regexp.MustCompile(`0x[0-9a-f]{6,}`)
+
+// Normalize replaces memory addresses in out with 0xADDR, so that output
+// can be compared across runs.
+func Normalize(out []byte) []byte {
+	return addrRE.ReplaceAll(out, []byte("0xADDR"))
+}
+
+// GoldenPath returns the file holding the expected output of the lesson
+// called name.
+func GoldenPath(dir, name string) string {
+	return filepath.Join(dir, "testdata", name+".golden")
+}
+
+// Check compares the normalized output of a lesson with its golden file
+// and returns a description of the differences, or "" if there are none.
+func Check(dir, name string, out []byte) (string, error) {
+	want, err := os.ReadFile(GoldenPath(dir, name))
+	if err != nil {
+		return "", err
+	}
+	return Diff(want, Normalize(out)), nil
+}
+
+// Update writes the normalized output of a lesson to its golden file.
+func Update(dir, name string, out []byte) error {
+	path := GoldenPath(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, Normalize(out), 0o644)
+}
+
+// Diff returns a line-by-line comparison of want and got, marking lines
+// only in want with "-" and lines only in got with "+". It returns "" if
+// they are equal.
+func Diff(want, got []byte) string {
+	if bytes.Equal(want, got) {
+		return ""
+	}
+	a := strings.Split(strings.TrimSuffix(string(want), "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(string(got), "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var d strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&d, "  %s\n", a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&d, "- %s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&d, "+ %s\n", b[j])
+			j++
+		}
+	}
+	return d.String()
+}
@@ -0,0 +1,306 @@
+package lesson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"basics/internal/txtar"
+)
+
+// Meta reads the lesson annotation recorded in the go.mod of an
+// exported lesson. File is the archive's source file.
+func Meta(ar *txtar.Archive) (Lesson, error) {
+	mod := ar.Lookup("go.mod")
+	if mod == nil {
+		return Lesson{}, errors.New("not a lesson archive: no go.mod")
+	}
+	line, _, _ := strings.Cut(string(mod.Data), "\n")
+	text, ok := strings.CutPrefix(line, annotationPrefix)
+	if !ok {
+		return Lesson{}, errors.New("not a lesson archive: go.mod does not start with a \"// lesson:\" comment")
+	}
+	// The function name is not part of an annotation, which sits on the
+	// function itself.
+	var run string
+	var rest []string
+	for _, f := range strings.Fields(text) {
+		if fn, ok := strings.CutPrefix(f, "run="); ok {
+			run = fn
+		} else {
+			rest = append(rest, f)
+		}
+	}
+	l, err := parseAnnotation(strings.Join(rest, " "))
+	if err != nil {
+		return Lesson{}, fmt.Errorf("go.mod: %v", err)
+	}
+	l.Func = run
+	if l.Func == "" {
+		return Lesson{}, fmt.Errorf("lesson %s: go.mod comment has no run=", l.Name)
+	}
+	for _, f := range ar.Files {
+		if strings.HasSuffix(f.Name, ".go") && f.Name != playFile && !strings.Contains(f.Name, "/") {
+			l.File = f.Name
+		}
+	}
+	if l.File == "" {
+		return Lesson{}, fmt.Errorf("lesson %s: archive has no source file", l.Name)
+	}
+	return l, nil
+}
+
+// Import adds an exported lesson to the package in dir under the given
+// name, or the archive's name if name is "". Declarations the package
+// already has are skipped if they are identical, so re-importing an
+// exported lesson adds only its annotation and golden file; a
+// declaration that differs is an error. Nothing is written unless the
+// whole import can succeed.
+func Import(dir string, ar *txtar.Archive, name string) (Lesson, error) {
+	l, err := Meta(ar)
+	if err != nil {
+		return Lesson{}, err
+	}
+	golden := ar.Lookup("testdata/" + l.Name + ".golden")
+	if golden == nil {
+		return Lesson{}, fmt.Errorf("lesson %s: archive has no testdata/%s.golden", l.Name, l.Name)
+	}
+	if name != "" {
+		l.Name = name
+	}
+	lessons, err := Load(dir)
+	if err != nil {
+		return Lesson{}, err
+	}
+	if _, err := Find(lessons, l.Name); err == nil {
+		return Lesson{}, fmt.Errorf("lesson %s already exists", l.Name)
+	}
+	p, err := loadSource(dir)
+	if err != nil {
+		return Lesson{}, err
+	}
+	have := make(map[string][]byte) // declared name -> printed declaration
+	for _, f := range p.files {
+		for _, d := range f.Decls {
+			for _, n := range declNames(d) {
+				have[n] = printDecl(p.fset, d)
+			}
+		}
+	}
+
+	data := ar.Lookup(l.File).Data
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, l.File, data, parser.ParseComments)
+	if err != nil {
+		return Lesson{}, err
+	}
+	var keep []ast.Decl
+	found := false
+	for _, d := range f.Decls {
+		names := declNames(d)
+		if len(names) == 0 {
+			continue // imports
+		}
+		dup := 0
+		for _, n := range names {
+			if n == l.Func {
+				found = true
+			}
+			if old, ok := have[n]; ok {
+				if !bytes.Equal(old, printDecl(fset, d)) {
+					return Lesson{}, fmt.Errorf("%s: %s is already declared differently in %s", fset.Position(d.Pos()), n, dir)
+				}
+				dup++
+			}
+		}
+		switch dup {
+		case 0:
+			keep = append(keep, d)
+		case len(names):
+		default:
+			return Lesson{}, fmt.Errorf("%s: declaration is partly declared in %s already", fset.Position(d.Pos()), dir)
+		}
+	}
+	if !found {
+		return Lesson{}, fmt.Errorf("lesson %s: %s does not declare %s", l.Name, l.File, l.Func)
+	}
+
+	anchors, err := Anchors(filepath.Join(dir, "README.md"))
+	if err != nil {
+		return Lesson{}, err
+	}
+	if _, ok := anchors[l.Section]; !ok {
+		return Lesson{}, fmt.Errorf("lesson %s: README.md has no section #%s", l.Name, l.Section)
+	}
+
+	var path string
+	var src []byte
+	if len(keep) > 0 {
+		path = filepath.Join(dir, l.File)
+		if _, err := os.Stat(path); err == nil {
+			return Lesson{}, fmt.Errorf("%s already exists", path)
+		}
+		src, err = rebuild(fset, f, data, keep)
+		if err != nil {
+			return Lesson{}, err
+		}
+	} else {
+		// Every declaration is already here: annotate the existing
+		// function.
+		for _, pf := range p.files {
+			for _, d := range pf.Decls {
+				if fd, ok := d.(*ast.FuncDecl); ok && fd.Recv == nil && fd.Name.Name == l.Func {
+					path, src = p.fset.Position(pf.Package).Filename, p.src[pf]
+				}
+			}
+		}
+	}
+	src, err = annotate(src, l)
+	if err != nil {
+		return Lesson{}, err
+	}
+
+	if err := os.WriteFile(path, src, 0o644); err != nil {
+		return Lesson{}, err
+	}
+	if _, err := WriteRegistry(dir); err != nil {
+		return Lesson{}, err
+	}
+	l.File = path
+	return l, Update(dir, l.Name, golden.Data)
+}
+
+// annotate adds the annotation declaring l to the doc comment of its
+// function in src.
+func annotate(src []byte, l Lesson) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range f.Decls {
+		if fd, ok := d.(*ast.FuncDecl); ok && fd.Recv == nil && fd.Name.Name == l.Func {
+			at := fset.Position(fd.Pos()).Offset
+			out := append(src[:at:at], annotation(l)+"\n"...)
+			return append(out, src[at:]...), nil
+		}
+	}
+	return nil, fmt.Errorf("no function %s", l.Func)
+}
+
+// stripAnnotations removes "// lesson:" lines from the source of a
+// declaration, so that a lesson keeps only the name it is imported as.
+func stripAnnotations(src []byte) []byte {
+	var out []byte
+	for _, line := range bytes.SplitAfter(src, []byte("\n")) {
+		if !bytes.HasPrefix(bytes.TrimSpace(line), []byte(annotationPrefix)) {
+			out = append(out, line...)
+		}
+	}
+	return out
+}
+
+// declNames returns the package-level names d declares; methods are
+// named "T.M".
+func declNames(d ast.Decl) []string {
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		if d.Recv == nil || len(d.Recv.List) == 0 {
+			return []string{d.Name.Name}
+		}
+		t := d.Recv.List[0].Type
+		if s, ok := t.(*ast.StarExpr); ok {
+			t = s.X
+		}
+		if id, ok := t.(*ast.Ident); ok {
+			return []string{id.Name + "." + d.Name.Name}
+		}
+	case *ast.GenDecl:
+		var names []string
+		for _, spec := range d.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, spec.Name.Name)
+			case *ast.ValueSpec:
+				for _, id := range spec.Names {
+					names = append(names, id.Name)
+				}
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// printDecl returns d formatted without its doc comment, for comparing
+// declarations regardless of layout and documentation.
+func printDecl(fset *token.FileSet, d ast.Decl) []byte {
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		c := *d
+		c.Doc = nil
+		var b bytes.Buffer
+		format.Node(&b, fset, &c)
+		return b.Bytes()
+	case *ast.GenDecl:
+		c := *d
+		c.Doc = nil
+		var b bytes.Buffer
+		format.Node(&b, fset, &c)
+		return b.Bytes()
+	}
+	return nil
+}
+
+// rebuild returns file f with only the declarations in keep and the
+// imports they use.
+func rebuild(fset *token.FileSet, f *ast.File, data []byte, keep []ast.Decl) ([]byte, error) {
+	used := make(map[string]bool)
+	for _, d := range keep {
+		ast.Inspect(d, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if id, ok := sel.X.(*ast.Ident); ok {
+					used[id.Name] = true
+				}
+			}
+			return true
+		})
+	}
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+	var b bytes.Buffer
+	b.Write(data[:offset(f.Package)])
+	b.WriteString("package main\n\n")
+	var imports []string
+	for _, imp := range f.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		if used[name] {
+			imports = append(imports, string(data[offset(imp.Pos()):offset(imp.End())]))
+		}
+	}
+	if len(imports) > 0 {
+		b.WriteString("import (\n\t" + strings.Join(imports, "\n\t") + "\n)\n")
+	}
+	cmap := ast.NewCommentMap(fset, f, f.Comments)
+	for _, d := range keep {
+		start, end := d.Pos(), d.End()
+		for _, c := range cmap[d] {
+			start, end = min(start, c.Pos()), max(end, c.End())
+		}
+		b.WriteString("\n")
+		b.Write(stripAnnotations(data[offset(start):offset(end)]))
+		b.WriteString("\n")
+	}
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,133 @@
+// Package lesson finds, builds and runs the lessons of the module.
+//
+// Lessons are functions in the module's root package, annotated with
+// "// lesson:" comments and listed in the lessons table that cmd/lessongen
+// generates from them into lessons_gen.go. The table is read from source,
+// so the tool never needs to import package main; to run a lesson, the
+// package is built once and the binary is started with BASICS_LESSON set.
+package lesson
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/version"
+	"path/filepath"
+	"strconv"
+)
+
+// Lesson is one entry of the lessons table.
+type Lesson struct {
+	Name      string
+	Func      string // function implementing the lesson
+	Section   string // README anchor the lesson illustrates
+	GoVersion string // minimum Go version, e.g. "go1.23"; empty for any
+	GOOS      string // the only GOOS the lesson runs on; empty for any
+	File      string // file declaring Func
+}
+
+// Load reads the lessons table of the package in dir.
+func Load(dir string) ([]Lesson, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var table *ast.CompositeLit
+	files := make(map[string]string) // function name -> file
+	for _, name := range bp.GoFiles {
+		path := filepath.Join(dir, name)
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range f.Decls {
+			switch d := d.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil {
+					files[d.Name.Name] = path
+				}
+			case *ast.GenDecl:
+				if lit := lessonsTable(d); lit != nil {
+					table = lit
+				}
+			}
+		}
+	}
+	if table == nil {
+		return nil, fmt.Errorf("%s: no lessons table", dir)
+	}
+	var lessons []Lesson
+	for _, elt := range table.Elts {
+		lit, ok := elt.(*ast.CompositeLit)
+		if !ok {
+			return nil, fmt.Errorf("%s: lessons entry is not a literal", fset.Position(elt.Pos()))
+		}
+		var l Lesson
+		for _, e := range lit.Elts {
+			kv, ok := e.(*ast.KeyValueExpr)
+			if !ok {
+				return nil, fmt.Errorf("%s: lessons entry must use keyed fields", fset.Position(e.Pos()))
+			}
+			switch key := kv.Key.(*ast.Ident).Name; key {
+			case "run":
+				id, ok := kv.Value.(*ast.Ident)
+				if !ok {
+					return nil, fmt.Errorf("%s: run must name a function", fset.Position(kv.Value.Pos()))
+				}
+				l.Func = id.Name
+			default:
+				bl, ok := kv.Value.(*ast.BasicLit)
+				if !ok || bl.Kind != token.STRING {
+					return nil, fmt.Errorf("%s: %s must be a string literal", fset.Position(kv.Value.Pos()), key)
+				}
+				s, _ := strconv.Unquote(bl.Value)
+				switch key {
+				case "name":
+					l.Name = s
+				case "section":
+					l.Section = s
+				case "goVersion":
+					if !version.IsValid(s) {
+						return nil, fmt.Errorf("%s: invalid goVersion %q", fset.Position(bl.Pos()), s)
+					}
+					l.GoVersion = s
+				case "goos":
+					l.GOOS = s
+				}
+			}
+		}
+		l.File = files[l.Func]
+		lessons = append(lessons, l)
+	}
+	return lessons, nil
+}
+
+// lessonsTable returns the composite literal of "var lessons = ..." in d.
+func lessonsTable(d *ast.GenDecl) *ast.CompositeLit {
+	if d.Tok != token.VAR {
+		return nil
+	}
+	for _, spec := range d.Specs {
+		vs := spec.(*ast.ValueSpec)
+		for i, id := range vs.Names {
+			if id.Name == "lessons" && i < len(vs.Values) {
+				lit, _ := vs.Values[i].(*ast.CompositeLit)
+				return lit
+			}
+		}
+	}
+	return nil
+}
+
+// Find returns the lesson called name.
+func Find(lessons []Lesson, name string) (Lesson, error) {
+	for _, l := range lessons {
+		if l.Name == name {
+			return l, nil
+		}
+	}
+	return Lesson{}, fmt.Errorf("no lesson %q", name)
+}
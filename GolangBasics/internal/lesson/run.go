@@ -0,0 +1,118 @@
+package lesson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// Build is a compiled copy of the lessons package in a temporary
+// directory. Close removes it.
+type Build struct {
+	Dir string // module directory
+	tmp string
+	bin string
+}
+
+// BuildError is returned when the lessons package does not compile.
+type BuildError struct {
+	Output []byte
+}
+
+func (e *BuildError) Error() string {
+	return fmt.Sprintf("build failed:\n%s", e.Output)
+}
+
+// NewBuild compiles the package in dir. Extra environment variables for
+// the go command, such as GOARCH=386, are passed in env.
+func NewBuild(ctx context.Context, dir string, env ...string) (*Build, error) {
+	return NewOverlayBuild(ctx, dir, nil, env...)
+}
+
+// NewOverlayBuild is like NewBuild, but compiles replace[path] in place of
+// each file path of the package, using the go command's -overlay flag, so
+// that an instrumented copy of a lesson can be built without touching the
+// source.
+func NewOverlayBuild(ctx context.Context, dir string, replace map[string][]byte, env ...string) (*Build, error) {
+	tmp, err := os.MkdirTemp("", "basics-lessons-")
+	if err != nil {
+		return nil, err
+	}
+	bin := filepath.Join(tmp, "lessons")
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+	args := []string{"build", "-o", bin}
+	if len(replace) > 0 {
+		overlay, err := writeOverlay(tmp, replace)
+		if err != nil {
+			os.RemoveAll(tmp)
+			return nil, err
+		}
+		args = append(args, "-overlay", overlay)
+	}
+	cmd := exec.CommandContext(ctx, "go", append(args, ".")...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		os.RemoveAll(tmp)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &BuildError{Output: out}
+	}
+	return &Build{Dir: dir, tmp: tmp, bin: bin}, nil
+}
+
+// writeOverlay writes the replacement files and the -overlay JSON file
+// naming them into tmp, and returns the JSON file's path.
+func writeOverlay(tmp string, replace map[string][]byte) (string, error) {
+	overlay := struct{ Replace map[string]string }{Replace: make(map[string]string)}
+	i := 0
+	for path, src := range replace {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return "", err
+		}
+		i++
+		file := filepath.Join(tmp, fmt.Sprintf("overlay%d_%s", i, filepath.Base(path)))
+		if err := os.WriteFile(file, src, 0o644); err != nil {
+			return "", err
+		}
+		overlay.Replace[abs] = file
+	}
+	data, err := json.Marshal(overlay)
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Join(tmp, "overlay.json")
+	return file, os.WriteFile(file, data, 0o644)
+}
+
+// Run runs the lesson called name and returns its combined output.
+// Standard output and standard error share one pipe, so println and
+// fmt.Println lines appear in the order the lesson printed them. Extra
+// environment variables, such as BASICS_BENCH=1, are passed in env.
+func (b *Build) Run(ctx context.Context, name string, env ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, b.bin)
+	cmd.Env = append(os.Environ(), "BASICS_LESSON="+name)
+	cmd.Env = append(cmd.Env, env...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return out.Bytes(), ctx.Err()
+	}
+	return out.Bytes(), err
+}
+
+// Close removes the build directory.
+func (b *Build) Close() error {
+	return os.RemoveAll(b.tmp)
+}
@@ -0,0 +1,94 @@
+package lesson
+
+import (
+	"bufio"
+	"fmt"
+	"go/version"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// MinVersion returns the Go version the lesson needs, "go1" if it
+// declares none.
+func (l Lesson) MinVersion() string {
+	if l.GoVersion == "" {
+		return "go1"
+	}
+	return l.GoVersion
+}
+
+// ModuleGoVersion returns the go directive of dir/go.mod as a Go
+// version, e.g. "go1.23.3".
+func ModuleGoVersion(dir string) (string, error) {
+	f, err := os.Open(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && fields[0] == "go" {
+			v := "go" + fields[1]
+			if !version.IsValid(v) {
+				return "", fmt.Errorf("go.mod: invalid go directive %q", fields[1])
+			}
+			return v, nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	return "go1.16", nil // the go command's default for modules without a directive
+}
+
+// VersionError reports a lesson that needs a newer Go than is available.
+type VersionError struct {
+	Lesson string
+	Need   string // version the lesson declares
+	Have   string // version available
+	What   string // "module" or "toolchain"
+}
+
+func (e *VersionError) Error() string {
+	if e.What == "module" {
+		return fmt.Sprintf("lesson %s requires %s but go.mod declares %s", e.Lesson, e.Need, e.Have)
+	}
+	return fmt.Sprintf("lesson %s requires %s but the toolchain is %s", e.Lesson, e.Need, e.Have)
+}
+
+// OSError reports a lesson that does not run on this operating system.
+type OSError struct {
+	Lesson string
+	Need   string // GOOS the lesson declares
+	Have   string // runtime.GOOS
+}
+
+func (e *OSError) Error() string {
+	return fmt.Sprintf("lesson %s runs only on %s, not %s", e.Lesson, e.Need, e.Have)
+}
+
+// CheckOS reports whether lesson l runs on this operating system.
+func CheckOS(l Lesson) error {
+	if l.GOOS != "" && l.GOOS != runtime.GOOS {
+		return &OSError{Lesson: l.Name, Need: l.GOOS, Have: runtime.GOOS}
+	}
+	return nil
+}
+
+// CheckVersion reports whether lesson l can be compiled under the
+// module's go directive modGo and run by this toolchain. A development
+// toolchain is assumed to be new enough.
+func CheckVersion(l Lesson, modGo string) error {
+	need := l.MinVersion()
+	if version.Compare(version.Lang(modGo), version.Lang(need)) < 0 {
+		return &VersionError{Lesson: l.Name, Need: need, Have: modGo, What: "module"}
+	}
+	tool := runtime.Version()
+	if version.IsValid(tool) && version.Compare(tool, need) < 0 {
+		return &VersionError{Lesson: l.Name, Need: need, Have: tool, What: "toolchain"}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+// Package modload loads and type-checks the packages of a module with
+// help from the go command: go list supplies each package's files and
+// the compiled export data of its dependencies, so imports of the
+// module's own packages resolve as well as the standard library.
+//
+// Load leaves test files out; LoadTests loads them too.
+package modload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Package is the subset of go list -json output the tools use.
+type Package struct {
+	ImportPath string
+	Dir        string
+	Name       string
+	Module     *struct{ Path string }
+	Standard   bool
+	DepOnly    bool
+	ForTest    string // for a test variant, the package under test
+	GoFiles    []string
+	Imports    []string
+	ImportMap  map[string]string // import path in the source → ImportPath
+	Export     string
+	Error      *struct{ Err string }
+}
+
+// List runs go list -json with args in dir and decodes the packages.
+func List(ctx context.Context, dir string, args ...string) ([]*Package, error) {
+	cmd := exec.CommandContext(ctx, "go", append([]string{"list", "-e", "-json"}, args...)...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %v\n%s", err, stderr.Bytes())
+	}
+	return Decode(bytes.NewReader(out))
+}
+
+// Decode decodes the packages of go list -json output.
+func Decode(r io.Reader) ([]*Package, error) {
+	var pkgs []*Package
+	dec := json.NewDecoder(r)
+	for {
+		p := new(Package)
+		if err := dec.Decode(p); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
+
+// Loaded is a parsed and type-checked package.
+type Loaded struct {
+	*Package
+	Fset  *token.FileSet
+	Files []*ast.File
+	Types *types.Package
+	Info  *types.Info
+}
+
+// Load type-checks the packages matching patterns in the module at dir.
+// All packages share one FileSet. Comments are kept.
+func Load(ctx context.Context, dir string, patterns ...string) ([]*Loaded, error) {
+	return load(ctx, dir, false, patterns)
+}
+
+// LoadTests is Load with the test files of each package: a package with
+// _test.go files of its own is loaded as its test variant, which
+// includes them, and an external test package is loaded as a package of
+// its own, named p_test. A package that imports a test variant, as an
+// external test package does, gets an importer of its own.
+func LoadTests(ctx context.Context, dir string, patterns ...string) ([]*Loaded, error) {
+	return load(ctx, dir, true, patterns)
+}
+
+func load(ctx context.Context, dir string, tests bool, patterns []string) ([]*Loaded, error) {
+	args := []string{"-export", "-deps"}
+	if tests {
+		args = append(args, "-test")
+	}
+	pkgs, err := List(ctx, dir, append(args, patterns...)...)
+	if err != nil {
+		return nil, err
+	}
+	exports := make(map[string]string)
+	variants := make(map[string]bool) // packages with a test variant
+	for _, p := range pkgs {
+		if p.Export != "" {
+			exports[p.ImportPath] = p.Export
+		}
+		if p.ForTest != "" && p.ForTest == path(p) {
+			variants[p.ForTest] = true
+		}
+	}
+	fset := token.NewFileSet()
+	importerFor := func(importMap map[string]string) types.Importer {
+		return importer.ForCompiler(fset, "gc", func(path string) (io.ReadCloser, error) {
+			if p, ok := importMap[path]; ok {
+				path = p
+			}
+			file, ok := exports[path]
+			if !ok {
+				return nil, fmt.Errorf("no export data for %s", path)
+			}
+			return os.Open(file)
+		})
+	}
+	imp := importerFor(nil)
+
+	var loaded []*Loaded
+	for _, p := range pkgs {
+		// The generated main package of a test binary is skipped, and so
+		// are a package whose test variant is loaded instead and a package
+		// recompiled against another's test variant.
+		if p.DepOnly || strings.HasSuffix(p.ImportPath, ".test") || variants[p.ImportPath] {
+			continue
+		}
+		if p.ForTest != "" && path(p) != p.ForTest && path(p) != p.ForTest+"_test" {
+			continue
+		}
+		if p.Error != nil {
+			return nil, errors.New(p.Error.Err)
+		}
+		l := &Loaded{
+			Package: p,
+			Fset:    fset,
+			Info: &types.Info{
+				Types:      make(map[ast.Expr]types.TypeAndValue),
+				Defs:       make(map[*ast.Ident]types.Object),
+				Uses:       make(map[*ast.Ident]types.Object),
+				Selections: make(map[*ast.SelectorExpr]*types.Selection),
+			},
+		}
+		for _, name := range p.GoFiles {
+			f, err := parser.ParseFile(fset, filepath.Join(p.Dir, name), nil, parser.ParseComments)
+			if err != nil {
+				return nil, err
+			}
+			l.Files = append(l.Files, f)
+		}
+		conf := types.Config{Importer: imp}
+		if len(p.ImportMap) > 0 {
+			conf.Importer = importerFor(p.ImportMap)
+		}
+		if l.Types, err = conf.Check(path(p), fset, l.Files, l.Info); err != nil {
+			return nil, err
+		}
+		loaded = append(loaded, l)
+	}
+	return loaded, nil
+}
+
+// path returns the import path of p without the " [p.test]" suffix of a
+// test variant.
+func path(p *Package) string {
+	path, _, _ := strings.Cut(p.ImportPath, " [")
+	return path
+}
@@ -0,0 +1,514 @@
+// Package similar finds exercise submissions that share code. Each
+// submission is normalized so that renaming variables, rewording comments,
+// changing literals or reformatting does not hide a copy, then
+// fingerprinted by winnowing the hashes of its token k-grams (Schleimer,
+// Wilkerson and Aiken, "Winnowing: Local Algorithms for Document
+// Fingerprinting", 2003). Two submissions are similar when they share many
+// fingerprints.
+package similar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Token is one normalized token of a submission.
+type Token struct {
+	Text string // normalized: identifiers and literals are canonical
+	Pos  token.Position
+}
+
+// Submission is the normalized source of one student's work.
+type Submission struct {
+	Name   string
+	Files  map[string][]string // source lines by file name
+	Tokens []Token
+	Errors []error // syntax errors of files that were tokenized unparsed
+}
+
+// Load reads the submissions in dir: each subdirectory is one submission
+// made of the .go files below it, and each .go file directly in dir is a
+// submission on its own.
+func Load(dir string) ([]*Submission, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var subs []*Submission
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		switch {
+		case e.IsDir():
+			s, err := LoadDir(path)
+			if err != nil {
+				return nil, err
+			}
+			subs = append(subs, s)
+		case strings.HasSuffix(e.Name(), ".go"):
+			s := &Submission{Name: e.Name(), Files: make(map[string][]string)}
+			if err := s.add(path, e.Name()); err != nil {
+				return nil, err
+			}
+			subs = append(subs, s)
+		}
+	}
+	return subs, nil
+}
+
+// LoadDir reads the .go files below dir as one submission named after dir.
+func LoadDir(dir string) (*Submission, error) {
+	s := &Submission{Name: filepath.Base(dir), Files: make(map[string][]string)}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return s.add(path, rel)
+	})
+	return s, err
+}
+
+// add appends the normalized tokens of the file at path, naming it name.
+// A file that does not parse is tokenized anyway, since submissions often
+// do not compile, and its syntax error is recorded in s.Errors.
+func (s *Submission) add(path, name string) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	toks, err := Normalize(name, src)
+	var errs scanner.ErrorList
+	if errors.As(err, &errs) {
+		for _, e := range errs {
+			e.Pos.Filename = path
+		}
+		s.Errors = append(s.Errors, errs.Err())
+		toks, err = tokenize(name, src), nil
+	}
+	if err != nil {
+		return err
+	}
+	s.Files[name] = strings.Split(string(src), "\n")
+	s.Tokens = append(s.Tokens, toks...)
+	return nil
+}
+
+// Normalize parses src and returns its tokens with comments and
+// semicolons dropped, every identifier the program declares renamed to
+// "v", and every literal replaced by a canonical literal of its kind.
+// Keywords, operators, predeclared names (int, len, nil) and the names of
+// imported packages and their members are kept, since they carry the
+// structure of the solution rather than the student's choice of words.
+func Normalize(name string, src []byte) ([]Token, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, name, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	keep := kept(f)
+
+	var toks []Token
+	var sc scanner.Scanner
+	file := fset.File(f.Pos())
+	sc.Init(file, src, nil, 0) // comments are skipped
+	for {
+		pos, tok, lit := sc.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON {
+			continue
+		}
+		toks = append(toks, Token{Text: canonical(tok, lit, keep[pos]), Pos: fset.Position(pos)})
+	}
+	return toks, nil
+}
+
+// tokenize is Normalize for source that does not parse. Without a syntax
+// tree it cannot tell which names the file declares, so it keeps the
+// package name, the predeclared names, the imported package names used before a dot and
+// the names selected from them, and renames every other identifier.
+func tokenize(name string, src []byte) []Token {
+	fset := token.NewFileSet()
+	imports := make(map[string]bool)
+	if f, _ := parser.ParseFile(fset, name, src, parser.ImportsOnly); f != nil {
+		for _, imp := range f.Imports {
+			imports[importName(imp)] = true
+		}
+	}
+	type scanned struct {
+		pos token.Pos
+		tok token.Token
+		lit string
+	}
+	var all []scanned
+	var sc scanner.Scanner
+	file := fset.AddFile(name, -1, len(src))
+	sc.Init(file, src, func(token.Position, string) {}, 0)
+	for {
+		pos, tok, lit := sc.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok != token.SEMICOLON {
+			all = append(all, scanned{pos, tok, lit})
+		}
+	}
+	toks := make([]Token, len(all))
+	for i, t := range all {
+		keep := t.tok == token.IDENT && types.Universe.Lookup(t.lit) != nil
+		if i == 1 && all[0].tok == token.PACKAGE {
+			keep = true
+		}
+		if t.tok == token.IDENT && imports[t.lit] && i+1 < len(all) && all[i+1].tok == token.PERIOD {
+			keep = true
+		}
+		if i >= 2 && all[i-1].tok == token.PERIOD && all[i-2].tok == token.IDENT && imports[all[i-2].lit] {
+			keep = true
+		}
+		toks[i] = Token{Text: canonical(t.tok, t.lit, keep), Pos: fset.Position(t.pos)}
+	}
+	return toks
+}
+
+// canonical returns the normalized text of a token: "v" for an identifier
+// that is not kept, and a fixed literal of each kind.
+func canonical(tok token.Token, lit string, keep bool) string {
+	switch tok {
+	case token.IDENT:
+		if keep {
+			return lit
+		}
+		return "v"
+	case token.INT, token.FLOAT, token.IMAG:
+		return "0"
+	case token.CHAR:
+		return "'x'"
+	case token.STRING:
+		return `""`
+	}
+	return tok.String()
+}
+
+// kept returns the positions of the identifiers in f that Normalize keeps:
+// package names, imported package names and the members selected from
+// them, and predeclared names the file does not redeclare.
+func kept(f *ast.File) map[token.Pos]bool {
+	declared := make(map[string]bool)
+	imports := make(map[string]bool)
+	for _, imp := range f.Imports {
+		imports[importName(imp)] = true
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE {
+				for _, e := range n.Lhs {
+					if id, ok := e.(*ast.Ident); ok {
+						declared[id.Name] = true
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			for _, id := range n.Names {
+				declared[id.Name] = true
+			}
+		case *ast.TypeSpec:
+			declared[n.Name.Name] = true
+		case *ast.FuncDecl:
+			if n.Recv == nil {
+				declared[n.Name.Name] = true
+			}
+		case *ast.Field:
+			for _, id := range n.Names {
+				declared[id.Name] = true
+			}
+		}
+		return true
+	})
+
+	keep := map[token.Pos]bool{f.Name.Pos(): true}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ImportSpec:
+			if n.Name != nil {
+				keep[n.Name.Pos()] = true
+			}
+			return false
+		case *ast.SelectorExpr:
+			if x, ok := n.X.(*ast.Ident); ok && imports[x.Name] && !declared[x.Name] {
+				keep[x.Pos()] = true
+				keep[n.Sel.Pos()] = true
+				return false
+			}
+		case *ast.Ident:
+			if types.Universe.Lookup(n.Name) != nil && !declared[n.Name] {
+				keep[n.Pos()] = true
+			}
+		}
+		return true
+	})
+	return keep
+}
+
+// importName returns the name a file uses for an import: its explicit
+// name, or the last element of its path.
+func importName(imp *ast.ImportSpec) string {
+	if imp.Name != nil {
+		return imp.Name.Name
+	}
+	path := strings.Trim(imp.Path.Value, "\"`")
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// Fingerprint is a selected k-gram hash and the index of the k-gram's
+// first token.
+type Fingerprint struct {
+	Hash  uint64
+	Index int
+}
+
+// Winnow hashes every k-gram of toks and keeps the minimum hash of each
+// window of w consecutive hashes (the rightmost, on ties), recording each
+// selected k-gram once. Any match of at least w+k-1 tokens is then
+// guaranteed to share a fingerprint, and matches shorter than k are
+// ignored as noise.
+func Winnow(toks []Token, k, w int) []Fingerprint {
+	hashes := kgrams(toks, k)
+	w = min(w, len(hashes))
+	var fps []Fingerprint
+	last := -1
+	for start := 0; start+w <= len(hashes) && w > 0; start++ {
+		m := start
+		for i := start; i < start+w; i++ {
+			if hashes[i] <= hashes[m] {
+				m = i
+			}
+		}
+		if m != last {
+			fps = append(fps, Fingerprint{Hash: hashes[m], Index: m})
+			last = m
+		}
+	}
+	return fps
+}
+
+// kgrams returns the hash of each run of k tokens in toks.
+func kgrams(toks []Token, k int) []uint64 {
+	if len(toks) < k {
+		return nil
+	}
+	hashes := make([]uint64, len(toks)-k+1)
+	for i := range hashes {
+		h := fnv.New64a()
+		for _, t := range toks[i : i+k] {
+			h.Write([]byte(t.Text))
+			h.Write([]byte{0})
+		}
+		hashes[i] = h.Sum64()
+	}
+	return hashes
+}
+
+// Span is a range of source lines in one file of a submission.
+type Span struct {
+	File       string
+	Start, End int // 1-based, inclusive
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("%s:%d-%d", s.File, s.Start, s.End)
+}
+
+// Region is a stretch of code the two submissions of a Match share.
+type Region struct {
+	A, B           Span
+	AStart, BStart int // index of the first matching token
+	Tokens         int // length of the match in tokens
+}
+
+// Lines pairs the lines of r's two spans by their matching tokens, so that
+// comments, blank lines and reformatting do not push the excerpts out of
+// step. A zero line number means the other side continues on a line of
+// its own.
+func (m Match) Lines(r Region) [][2]int {
+	var rows [][2]int
+	last := [2]int{}
+	for i := range r.Tokens {
+		a, b := m.A.Tokens[r.AStart+i].Pos, m.B.Tokens[r.BStart+i].Pos
+		if a.Filename != r.A.File || b.Filename != r.B.File {
+			break
+		}
+		row := [2]int{a.Line, b.Line}
+		if row[0] == last[0] {
+			row[0] = 0
+		}
+		if row[1] == last[1] {
+			row[1] = 0
+		}
+		if row == [2]int{} {
+			continue
+		}
+		rows = append(rows, row)
+		last = [2]int{a.Line, b.Line}
+	}
+	return rows
+}
+
+// Match is the similarity of two submissions.
+type Match struct {
+	A, B     *Submission
+	ScoreA   float64 // fraction of A's fingerprints found in B
+	ScoreB   float64 // fraction of B's fingerprints found in A
+	Regions  []Region
+	Shared   int
+	Distinct [2]int // fingerprints of A and B
+}
+
+// Score is the larger of the two scores: a short submission copied whole
+// into a longer one is as suspicious as a full copy.
+func (m Match) Score() float64 {
+	return max(m.ScoreA, m.ScoreB)
+}
+
+// Options control fingerprinting and comparison.
+type Options struct {
+	K, W int
+	// Base holds k-gram hashes of code every submission may contain, such
+	// as the exercise's starter code; they are not counted as shared.
+	Base map[uint64]bool
+}
+
+// Compare fingerprints every submission and compares every pair, returning
+// the matches sorted by decreasing score. It stops with ctx's error once
+// ctx is canceled: a class of submissions has many pairs.
+func Compare(ctx context.Context, subs []*Submission, opts Options) ([]Match, error) {
+	fps := make([][]Fingerprint, len(subs))
+	for i, s := range subs {
+		for _, fp := range Winnow(s.Tokens, opts.K, opts.W) {
+			if !opts.Base[fp.Hash] {
+				fps[i] = append(fps[i], fp)
+			}
+		}
+	}
+	var matches []Match
+	for i := range subs {
+		for j := i + 1; j < len(subs); j++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			matches = append(matches, compare(subs[i], subs[j], fps[i], fps[j], opts.K))
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b Match) int {
+		switch {
+		case a.Score() > b.Score():
+			return -1
+		case a.Score() < b.Score():
+			return 1
+		}
+		return 0
+	})
+	return matches, nil
+}
+
+func compare(a, b *Submission, fa, fb []Fingerprint, k int) Match {
+	inB := make(map[uint64]int) // hash → first index in b
+	for _, fp := range fb {
+		if _, ok := inB[fp.Hash]; !ok {
+			inB[fp.Hash] = fp.Index
+		}
+	}
+	ha, hb := distinct(fa), distinct(fb)
+	m := Match{A: a, B: b, Distinct: [2]int{len(ha), len(hb)}}
+
+	// pairs are the token indexes of shared k-grams in a and b, in a's
+	// order; runs of pairs that advance together form a region.
+	type pair struct{ a, b int }
+	var pairs []pair
+	seen := make(map[uint64]bool)
+	for _, fp := range fa {
+		if j, ok := inB[fp.Hash]; ok {
+			pairs = append(pairs, pair{fp.Index, j})
+			if !seen[fp.Hash] {
+				seen[fp.Hash] = true
+				m.Shared++
+			}
+		}
+	}
+	if len(ha) > 0 {
+		m.ScoreA = float64(m.Shared) / float64(len(ha))
+	}
+	if len(hb) > 0 {
+		m.ScoreB = float64(m.Shared) / float64(len(hb))
+	}
+
+	for i := 0; i < len(pairs); {
+		start, end := pairs[i], pairs[i]
+		j := i + 1
+		for ; j < len(pairs); j++ {
+			p := pairs[j]
+			if p.a-end.a != p.b-end.b || p.a-end.a > k {
+				break
+			}
+			end = p
+		}
+		m.Regions = append(m.Regions, Region{
+			A:      span(a.Tokens, start.a, end.a+k-1),
+			B:      span(b.Tokens, start.b, end.b+k-1),
+			AStart: start.a,
+			BStart: start.b,
+			Tokens: end.a + k - start.a,
+		})
+		i = j
+	}
+	slices.SortStableFunc(m.Regions, func(x, y Region) int { return y.Tokens - x.Tokens })
+	return m
+}
+
+func distinct(fps []Fingerprint) map[uint64]bool {
+	m := make(map[uint64]bool)
+	for _, fp := range fps {
+		m[fp.Hash] = true
+	}
+	return m
+}
+
+// span returns the lines of toks[first:last+1]. A match that crosses into
+// the next file is cut at the end of the first.
+func span(toks []Token, first, last int) Span {
+	s := Span{File: toks[first].Pos.Filename, Start: toks[first].Pos.Line, End: toks[first].Pos.Line}
+	for _, t := range toks[first : last+1] {
+		if t.Pos.Filename != s.File {
+			break
+		}
+		s.End = t.Pos.Line
+	}
+	return s
+}
+
+// BaseHashes returns the hash of every k-gram of the starter code s, for
+// Options.Base. All k-grams are kept, not only the winnowed ones, because
+// a submission may select a different k-gram of the same code.
+func BaseHashes(s *Submission, k int) map[uint64]bool {
+	base := make(map[uint64]bool)
+	for _, h := range kgrams(s.Tokens, k) {
+		base[h] = true
+	}
+	return base
+}
@@ -0,0 +1,245 @@
+package similar
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func texts(toks []Token) string {
+	var s []string
+	for _, t := range toks {
+		s = append(s, t.Text)
+	}
+	return strings.Join(s, " ")
+}
+
+func normalized(t *testing.T, src string) string {
+	t.Helper()
+	toks, err := Normalize("x.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return texts(toks)
+}
+
+const sumSrc = `package main
+
+import "fmt"
+
+func main() {
+	total := 0
+	for i := 0; i < 10; i++ {
+		total += i * i
+	}
+	fmt.Println("sum:", total)
+}
+`
+
+func TestNormalize(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		other string
+		equal bool
+	}{
+		{"renamed identifiers", strings.NewReplacer("total", "acc", "i :=", "n :=", "i <", "n <", "i++", "n++", "i * i", "n * n").Replace(sumSrc), true},
+		{"changed literals", strings.NewReplacer("10", "0x20", `"sum:"`, "`total`").Replace(sumSrc), true},
+		{"comments and layout", strings.Replace(sumSrc, "\ttotal := 0\n", "\n\t// running total\n\ttotal :=    0\n", 1), true},
+		{"other fmt function", strings.Replace(sumSrc, "fmt.Println", "fmt.Print", 1), false},
+		{"other operator", strings.Replace(sumSrc, "i * i", "i + i", 1), false},
+		{"shadowed predeclared name", strings.NewReplacer("total", "len").Replace(sumSrc), true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := normalized(t, sumSrc), normalized(t, tt.other)
+			if (a == b) != tt.equal {
+				t.Errorf("equal = %v, want %v\n%s\n%s", a == b, tt.equal, a, b)
+			}
+		})
+	}
+	got := normalized(t, sumSrc)
+	for _, want := range []string{"fmt . Println", `( "" , v )`, "v := 0", "package main"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Normalize gave %q, which lacks %q", got, want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	// Without shadowing, tokenizing gives what Normalize gives
+	if got, want := texts(tokenize("x.go", []byte(sumSrc))), normalized(t, sumSrc); got != want {
+		t.Errorf("tokenize:\n%s\nNormalize:\n%s", got, want)
+	}
+	broken := strings.Replace(sumSrc, `fmt.Println("sum:", total)`, "x := ", 1)
+	if _, err := Normalize("x.go", []byte(broken)); err == nil {
+		t.Fatal("Normalize accepted a file that does not parse")
+	}
+	got := texts(tokenize("x.go", []byte(broken)))
+	if !strings.Contains(got, "v := 0 for v := 0") {
+		t.Errorf("tokenize of a broken file gave %q", got)
+	}
+}
+
+func TestWinnow(t *testing.T) {
+	same := func(n int) []Token {
+		toks := make([]Token, n)
+		for i := range toks {
+			toks[i].Text = "x"
+		}
+		return toks
+	}
+	for _, tt := range []struct {
+		name    string
+		toks    []Token
+		k, w    int
+		indexes []int
+	}{
+		// all hashes tie, so each window picks its rightmost k-gram
+		{"ties pick the rightmost", same(6), 1, 3, []int{2, 3, 4, 5}},
+		{"ties with k=2", same(6), 2, 2, []int{1, 2, 3, 4}},
+		{"fewer tokens than k", same(2), 3, 2, nil},
+		{"window wider than the k-grams", same(4), 2, 10, []int{2}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, fp := range Winnow(tt.toks, tt.k, tt.w) {
+				got = append(got, fp.Index)
+			}
+			if !slices.Equal(got, tt.indexes) {
+				t.Errorf("Winnow indexes %v, want %v", got, tt.indexes)
+			}
+		})
+	}
+
+	// Every window's minimum, rightmost on ties, is selected, and nothing
+	// else is
+	toks, err := Normalize("x.go", []byte(sumSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const k, w = 3, 4
+	hashes := kgrams(toks, k)
+	want := make(map[int]bool)
+	for start := 0; start+w <= len(hashes); start++ {
+		m := start
+		for i := start + 1; i < start+w; i++ {
+			if hashes[i] <= hashes[m] {
+				m = i
+			}
+		}
+		want[m] = true
+	}
+	fps := Winnow(toks, k, w)
+	if len(fps) != len(want) {
+		t.Errorf("Winnow selected %d k-grams, want %d", len(fps), len(want))
+	}
+	for _, fp := range fps {
+		if !want[fp.Index] || fp.Hash != hashes[fp.Index] {
+			t.Errorf("Winnow selected %+v, which is no window's minimum", fp)
+		}
+	}
+}
+
+func submission(t *testing.T, name, src string) *Submission {
+	t.Helper()
+	toks, err := Normalize("main.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Submission{Name: name, Files: map[string][]string{"main.go": strings.Split(src, "\n")}, Tokens: toks}
+}
+
+func TestCompare(t *testing.T) {
+	alice := submission(t, "alice", sumSrc)
+	// bob renamed everything and split a line in two
+	bob := submission(t, "bob", strings.NewReplacer(
+		"total", "acc",
+		"\tfor i := 0; i < 10; i++ {\n", "\tfor i := 0;\n\t\ti < 10; i++ {\n",
+	).Replace(sumSrc))
+	carol := submission(t, "carol", `package main
+
+import "strings"
+
+func main() {
+	var words []string
+	words = append(words, strings.Fields("a b c")...)
+	println(len(words))
+}
+`)
+	matches, err := Compare(context.Background(), []*Submission{alice, bob, carol}, Options{K: 5, W: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 3 {
+		t.Fatalf("%d matches, want 3", len(matches))
+	}
+	m := matches[0]
+	if m.A != alice || m.B != bob || m.ScoreA != 1 || m.ScoreB != 1 {
+		t.Fatalf("best match %s ~ %s (%.2f, %.2f), want alice ~ bob (1, 1)", m.A.Name, m.B.Name, m.ScoreA, m.ScoreB)
+	}
+	for _, other := range matches[1:] {
+		if other.Score() >= 0.5 {
+			t.Errorf("%s ~ %s scores %.2f, want below 0.5", other.A.Name, other.B.Name, other.Score())
+		}
+	}
+
+	if len(m.Regions) != 1 {
+		t.Fatalf("alice ~ bob: %d regions, want 1", len(m.Regions))
+	}
+	r := m.Regions[0]
+	// A region spans the selected fingerprints only: winnowing selects
+	// none in the package clause nor in the closing brace.
+	if r.A != (Span{"main.go", 3, 10}) || r.B != (Span{"main.go", 3, 11}) {
+		t.Errorf("region %v ~ %v, want main.go:3-10 ~ main.go:3-11", r.A, r.B)
+	}
+	// Line 7 of alice matches lines 7 and 8 of bob; the lines after it are
+	// one apart
+	want := [][2]int{{3, 3}, {5, 5}, {6, 6}, {7, 7}, {0, 8}, {8, 9}, {9, 10}, {10, 11}}
+	if got := m.Lines(r); !slices.Equal(got, want) {
+		t.Errorf("Lines = %v, want %v", got, want)
+	}
+}
+
+func TestCompareCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	subs := []*Submission{submission(t, "a", sumSrc), submission(t, "b", sumSrc)}
+	if _, err := Compare(ctx, subs, Options{K: 5, W: 4}); err != context.Canceled {
+		t.Errorf("Compare with a canceled context = %v, want context.Canceled", err)
+	}
+}
+
+func TestLoadUnparsable(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"alice/main.go": sumSrc,
+		"carol/main.go": "package main\n\nfunc main() {\n\tx := \n}\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	subs, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 2 {
+		t.Fatalf("loaded %d submissions, want 2", len(subs))
+	}
+	carol := subs[1]
+	if len(subs[0].Errors) != 0 || len(carol.Errors) != 1 {
+		t.Fatalf("errors %v and %v, want none and one", subs[0].Errors, carol.Errors)
+	}
+	if msg := carol.Errors[0].Error(); !strings.HasPrefix(msg, filepath.Join(dir, "carol", "main.go")+":5:1: ") {
+		t.Errorf("error %q does not start with the file's path and position", msg)
+	}
+	if len(carol.Tokens) == 0 {
+		t.Error("carol has no tokens")
+	}
+}
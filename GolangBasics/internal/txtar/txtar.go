@@ -0,0 +1,99 @@
+// Package txtar reads and writes txtar archives, the plain-text format the
+// Go Playground and the go command's tests use to hold several files:
+//
+//	optional comment
+//	-- go.mod --
+//	module example
+//	-- main.go --
+//	package main
+//
+// A line "-- name --" starts a file, which runs to the next such line.
+// File contents always end in a newline; Format adds one if it is
+// missing.
+package txtar
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Archive is a parsed txtar archive.
+type Archive struct {
+	Comment []byte
+	Files   []File
+}
+
+// File is one file of an archive.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Lookup returns the file called name, or nil.
+func (a *Archive) Lookup(name string) *File {
+	for i := range a.Files {
+		if a.Files[i].Name == name {
+			return &a.Files[i]
+		}
+	}
+	return nil
+}
+
+// Format returns the text of a.
+func Format(a *Archive) []byte {
+	var b bytes.Buffer
+	b.Write(fixNL(a.Comment))
+	for _, f := range a.Files {
+		b.WriteString("-- " + f.Name + " --\n")
+		b.Write(fixNL(f.Data))
+	}
+	return b.Bytes()
+}
+
+// Parse parses data as an archive. Every input is a valid archive: text
+// before the first file marker is the comment.
+func Parse(data []byte) *Archive {
+	a := new(Archive)
+	var name string
+	a.Comment, name, data = findFile(data)
+	for name != "" {
+		f := File{Name: name}
+		f.Data, name, data = findFile(data)
+		a.Files = append(a.Files, f)
+	}
+	return a
+}
+
+// findFile returns the text before the next file marker in data, the name
+// in that marker, and the text after it. name is "" if there is no marker.
+func findFile(data []byte) (before []byte, name string, after []byte) {
+	for i := 0; i < len(data); {
+		line := data[i:]
+		end := bytes.IndexByte(line, '\n')
+		if end >= 0 {
+			line = line[:end+1]
+		}
+		if name, ok := marker(line); ok {
+			return data[:i], name, data[i+len(line):]
+		}
+		i += len(line)
+	}
+	return data, "", nil
+}
+
+// marker reports whether line is "-- name --", and returns the name.
+func marker(line []byte) (string, bool) {
+	s := strings.TrimRight(string(line), "\r\n")
+	if !strings.HasPrefix(s, "-- ") || !strings.HasSuffix(s, " --") || len(s) < len("-- x --") {
+		return "", false
+	}
+	name := strings.TrimSpace(s[3 : len(s)-3])
+	return name, name != ""
+}
+
+func fixNL(data []byte) []byte {
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return data
+	}
+	return append(data[:len(data):len(data)], '\n')
+}
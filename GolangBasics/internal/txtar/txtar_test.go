@@ -0,0 +1,40 @@
+package txtar_test
+
+import (
+	"bytes"
+	"testing"
+
+	"basics/internal/txtar"
+)
+
+func TestFormatParse(t *testing.T) {
+	a := &txtar.Archive{
+		Comment: []byte("comment"),
+		Files: []txtar.File{
+			{Name: "go.mod", Data: []byte("module m\n")},
+			{Name: "main.go", Data: []byte("package main")},
+			{Name: "empty.txt"},
+			{Name: "testdata/x.golden", Data: []byte("-- not a marker\n--  --\n")},
+		},
+	}
+	const want = `comment
+-- go.mod --
+module m
+-- main.go --
+package main
+-- empty.txt --
+-- testdata/x.golden --
+-- not a marker
+--  --
+`
+	data := txtar.Format(a)
+	if string(data) != want {
+		t.Fatalf("Format:\n%s\nwant:\n%s", data, want)
+	}
+	if again := txtar.Format(txtar.Parse(data)); !bytes.Equal(again, data) {
+		t.Errorf("Format(Parse(data)):\n%s\nwant:\n%s", again, data)
+	}
+	if f := txtar.Parse(data).Lookup("main.go"); f == nil || string(f.Data) != "package main\n" {
+		t.Errorf("Lookup(main.go) = %v", f)
+	}
+}
@@ -0,0 +1,126 @@
+// Package unkeyed finds composite literals of struct types that list
+// their fields by position, such as example{3.14, 5, 10, 15, true}, and
+// rewrites them with field keys. Keyed literals keep compiling, with the
+// same meaning, when fields are reordered to reduce padding.
+package unkeyed
+
+import (
+	"bytes"
+	"context"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"os"
+	"slices"
+	"sort"
+
+	"basics/internal/modload"
+)
+
+// Literal is one unkeyed struct literal.
+type Literal struct {
+	Pos  token.Position
+	Type string   // type of the literal, e.g. "example"
+	Keys []string // field name for each element
+
+	elts []ast.Expr
+}
+
+// File is a source file with unkeyed struct literals.
+type File struct {
+	Path     string
+	Literals []Literal
+
+	fset *token.FileSet
+	src  []byte
+}
+
+// Find type-checks every package of the module at dir, with its test
+// files, and returns the files that contain unkeyed struct literals.
+func Find(ctx context.Context, dir string) ([]*File, error) {
+	pkgs, err := modload.LoadTests(ctx, dir, "./...")
+	if err != nil {
+		return nil, err
+	}
+	var files []*File
+	for _, p := range pkgs {
+		fs, err := find(p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, fs...)
+	}
+	return files, nil
+}
+
+// find collects the unkeyed literals of one package.
+func find(p *modload.Loaded) ([]*File, error) {
+	qualifier := func(pkg *types.Package) string {
+		if pkg == p.Types {
+			return ""
+		}
+		return pkg.Name()
+	}
+	var files []*File
+	for _, f := range p.Files {
+		path := p.Fset.Position(f.Pos()).Filename
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file := &File{Path: path, fset: p.Fset, src: src}
+		ast.Inspect(f, func(n ast.Node) bool {
+			lit, ok := n.(*ast.CompositeLit)
+			if !ok || len(lit.Elts) == 0 {
+				return true
+			}
+			if _, keyed := lit.Elts[0].(*ast.KeyValueExpr); keyed {
+				return true
+			}
+			t := p.Info.TypeOf(lit)
+			if ptr, ok := t.Underlying().(*types.Pointer); ok {
+				t = ptr.Elem()
+			}
+			st, ok := t.Underlying().(*types.Struct)
+			if !ok {
+				return true
+			}
+			l := Literal{
+				Pos:  p.Fset.Position(lit.Lbrace),
+				Type: types.TypeString(t, qualifier),
+				elts: lit.Elts,
+			}
+			for i := range lit.Elts {
+				l.Keys = append(l.Keys, st.Field(i).Name())
+			}
+			file.Literals = append(file.Literals, l)
+			return true
+		})
+		if len(file.Literals) > 0 {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// Rewrite returns the source of f with every unkeyed literal given field
+// keys, formatted with go/format.
+func (f *File) Rewrite() ([]byte, error) {
+	type edit struct {
+		off  int
+		text string
+	}
+	var edits []edit
+	for _, l := range f.Literals {
+		for i, e := range l.elts {
+			edits = append(edits, edit{off: f.fset.Position(e.Pos()).Offset, text: l.Keys[i] + ": "})
+		}
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].off > edits[j].off })
+	src := bytes.Clone(f.src)
+	for _, e := range edits {
+		src = slices.Insert(src, e.off, []byte(e.text)...)
+	}
+	return format.Source(src)
+}
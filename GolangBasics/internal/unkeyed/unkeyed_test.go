@@ -0,0 +1,158 @@
+package unkeyed
+
+import (
+	"context"
+	"go/format"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testModule = map[string]string{
+	"go.mod": "module m\n\ngo 1.22\n",
+	"geo/geo.go": `package geo
+
+type Point struct{ X, Y int }
+`,
+	"geo/geo_test.go": `package geo_test
+
+import "m/geo"
+
+var origin = geo.Point{0, 0}
+`,
+	"main.go": `package main
+
+import "m/geo"
+
+type inner struct{ n int }
+
+type outer struct {
+	inner
+	name string
+}
+
+var (
+	a = geo.Point{1, 2}
+	b = geo.Point{X: 1, Y: 2}
+	c = outer{inner{3}, "c"}
+	d = []*geo.Point{{3, 4}}
+	e = []int{1, 2}
+)
+
+func main() {}
+`,
+	"main_test.go": `package main
+
+var f = struct{ x, y int }{5, 6}
+`,
+}
+
+// keyed is main.go after Rewrite.
+const keyed = `package main
+
+import "m/geo"
+
+type inner struct{ n int }
+
+type outer struct {
+	inner
+	name string
+}
+
+var (
+	a = geo.Point{X: 1, Y: 2}
+	b = geo.Point{X: 1, Y: 2}
+	c = outer{inner: inner{n: 3}, name: "c"}
+	d = []*geo.Point{{X: 3, Y: 4}}
+	e = []int{1, 2}
+)
+
+func main() {}
+`
+
+func writeModule(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range testModule {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFind(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go list")
+	}
+	dir := writeModule(t)
+	files, err := Find(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type found struct {
+		file string
+		line int
+		typ  string
+		keys string
+	}
+	var got []found
+	for _, f := range files {
+		for _, l := range f.Literals {
+			rel, _ := filepath.Rel(dir, l.Pos.Filename)
+			got = append(got, found{filepath.ToSlash(rel), l.Pos.Line, l.Type, strings.Join(l.Keys, ",")})
+		}
+	}
+	slices.SortStableFunc(got, func(a, b found) int { return strings.Compare(a.file, b.file) })
+	want := []found{
+		{"geo/geo_test.go", 5, "geo.Point", "X,Y"}, // external test package
+		{"main.go", 13, "geo.Point", "X,Y"},
+		{"main.go", 15, "outer", "inner,name"}, // embedded field
+		{"main.go", 15, "inner", "n"},
+		{"main.go", 16, "geo.Point", "X,Y"}, // elided *geo.Point
+		{"main_test.go", 3, "struct{x int; y int}", "x,y"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Find:\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go list")
+	}
+	dir := writeModule(t)
+	files, err := Find(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		src, err := f.Rewrite()
+		if err != nil {
+			t.Fatalf("%s: %v", f.Path, err)
+		}
+		if formatted, err := format.Source(src); err != nil || string(formatted) != string(src) {
+			t.Errorf("%s: rewrite is not gofmt-formatted (%v):\n%s", f.Path, err, src)
+		}
+		if filepath.Base(f.Path) == "main.go" && string(src) != keyed {
+			t.Errorf("main.go rewritten to\n%s\nwant\n%s", src, keyed)
+		}
+		if err := os.WriteFile(f.Path, src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The rewritten module still type-checks, and has nothing left to key.
+	files, err = Find(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("after Rewrite: %v", err)
+	}
+	for _, f := range files {
+		t.Errorf("after Rewrite: %s still has %d unkeyed literals", f.Path, len(f.Literals))
+	}
+}
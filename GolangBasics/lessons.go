@@ -0,0 +1,119 @@
+// Lesson registry
+
+package main
+
+//go:generate go run ./cmd/lessongen
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"text/tabwriter"
+)
+
+// entry is one runnable lesson: a function in this package with no
+// arguments, and the README section it illustrates. The lessons table is
+// generated into lessons_gen.go from "// lesson:" annotations on the
+// functions; run go generate after adding one. The type is not called
+// lesson so that tests can import basics/internal/lesson by its name.
+type entry struct {
+	name      string
+	section   string // README anchor
+	goVersion string // minimum Go version, e.g. "go1.23"; empty for any
+	goos      string // the only GOOS the lesson runs on; empty for any
+	run       func()
+}
+
+// benchmarking reports whether the lesson should also run its benchmarks,
+// and print what may differ between Go releases. Timings differ on every
+// run, so "basics bench" sets BASICS_BENCH and "basics run", which
+// compares output with golden files, does not.
+func benchmarking() bool {
+	return os.Getenv("BASICS_BENCH") != ""
+}
+
+// Benchmarks store their results in sinks, so the compiler cannot delete
+// the work they measure as dead code.
+var (
+	floatSink float64
+	intSink   int
+	anySink   any
+)
+
+// benchTable runs benchmarks with testing.Benchmark and prints a row for
+// each, right-aligned: its name, optionally a size in bytes, the time per
+// operation in ns or µs and, if allocs is set, the allocations per
+// operation. Rows appear on flush.
+type benchTable struct {
+	tw     *tabwriter.Writer
+	micro  bool // µs/op instead of ns/op
+	allocs bool
+}
+
+// newBenchTable prints the header: the names of the leading columns, then
+// those of the time and allocations columns.
+func newBenchTable(micro, allocs bool, columns ...string) *benchTable {
+	t := &benchTable{tw: tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight), micro: micro, allocs: allocs}
+	if micro {
+		columns = append(columns, "µs/op")
+	} else {
+		columns = append(columns, "ns/op")
+	}
+	if allocs {
+		columns = append(columns, "allocs/op")
+	}
+	fmt.Fprintln(t.tw, strings.Join(columns, "\t")+"\t")
+	return t
+}
+
+// run benchmarks f and prints its row.
+func (t *benchTable) run(name string, f func(b *testing.B)) {
+	fmt.Fprintf(t.tw, "%s\t", name)
+	t.result(testing.Benchmark(f))
+}
+
+// runSize is run for a table with a size column.
+func (t *benchTable) runSize(name string, size uintptr, f func(b *testing.B)) {
+	fmt.Fprintf(t.tw, "%s\t%d\t", name, size)
+	t.result(testing.Benchmark(f))
+}
+
+func (t *benchTable) result(res testing.BenchmarkResult) {
+	ns := float64(res.T.Nanoseconds()) / float64(res.N)
+	if t.micro {
+		fmt.Fprintf(t.tw, "%.1f\t", ns/1e3)
+	} else {
+		fmt.Fprintf(t.tw, "%.2f\t", ns)
+	}
+	if t.allocs {
+		fmt.Fprintf(t.tw, "%d\t", res.AllocsPerOp())
+	}
+	fmt.Fprintln(t.tw)
+}
+
+func (t *benchTable) flush() {
+	t.tw.Flush()
+}
+
+// When BASICS_LESSON is set, the program runs that lesson instead of
+// main. This is how the basics tool runs lessons other than main.
+func init() {
+	name := os.Getenv("BASICS_LESSON")
+	if name == "" {
+		return
+	}
+	for _, l := range lessons {
+		if l.name == name {
+			if l.goos != "" && l.goos != runtime.GOOS {
+				fmt.Fprintf(os.Stderr, "lesson %q runs only on %s\n", name, l.goos)
+				os.Exit(2)
+			}
+			l.run()
+			os.Exit(0)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "no lesson %q\n", name)
+	os.Exit(2)
+}
@@ -0,0 +1,17 @@
+// Code generated by lessongen from the "// lesson:" annotations. DO NOT EDIT.
+
+package main
+
+var lessons = []entry{
+	{name: "syscalls", section: "system-calls-crossing-into-the-kernel", goVersion: "go1.22", goos: "linux", run: rawSyscalls},
+	{name: "preemption", section: "goroutine-preemption", goVersion: "go1.22", run: preemption},
+	{name: "main", section: "type-system--variables", run: main},
+	{name: "bignum", section: "explicit-type-conversion--casting", goVersion: "go1.9", run: bigArith},
+	{name: "formatting", section: "formatting-verbs--custom-formatters", run: formatting},
+	{name: "hostlayout", section: "zero-size-types--structshostlayout", goVersion: "go1.23", run: hostLayout},
+	{name: "mapkeys", section: "structs-as-map-keys", goVersion: "go1.19", run: mapKeys},
+	{name: "pointer", section: "pointers--reference-semantics", run: pointer},
+	{name: "devirt", section: "devirtualization--interface-call-cost", goVersion: "go1.1", run: devirt},
+	{name: "functions", section: "functions", goVersion: "go1.21", run: functions},
+	{name: "sorting", section: "sorting--ordering", goVersion: "go1.22", run: sorting},
+}
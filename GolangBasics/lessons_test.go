@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"go/version"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+
+	"basics/internal/lesson"
+)
+
+// TestLessons runs each registered lesson in a copy of the test binary,
+// through the BASICS_LESSON hook in init, and compares its output with
+// testdata/<name>.golden like "basics run".
+func TestLessons(t *testing.T) {
+	for _, l := range lessons {
+		t.Run(l.name, func(t *testing.T) {
+			if v := runtime.Version(); l.goVersion != "" && version.IsValid(v) && version.Compare(v, l.goVersion) < 0 {
+				t.Skipf("needs %s, have %s", l.goVersion, v)
+			}
+			if l.goos != "" && l.goos != runtime.GOOS {
+				t.Skipf("runs only on %s", l.goos)
+			}
+			cmd := exec.Command(os.Args[0])
+			cmd.Env = append(os.Environ(), "BASICS_LESSON="+l.name, "BASICS_BENCH=")
+			var out bytes.Buffer
+			cmd.Stdout = &out
+			cmd.Stderr = &out
+			if err := cmd.Run(); err != nil {
+				t.Fatalf("%v\n%s", err, out.Bytes())
+			}
+			diff, err := lesson.Check(".", l.name, out.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff != "" {
+				t.Errorf("output differs from %s:\n%s", lesson.GoldenPath(".", l.name), diff)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import "fmt"
 const Constant int = 10      // Exported constant (Public)
 const pvtConstant int = 20   // Unexported constant (Private)
 
+// lesson: main section=type-system--variables
 func main()  {
 	fmt.Println("Hello World")
 
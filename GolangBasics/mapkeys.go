@@ -0,0 +1,206 @@
+// Structs as map keys, and maps shared between goroutines
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"text/tabwriter"
+	"unsafe"
+)
+
+// Map keys of different layouts. All are comparable, so all can be keys;
+// what differs is the work the map's hash function has to do.
+
+// All integers, no padding: the key is hashed as one block of 16 bytes
+type intKey struct {
+	id  int64
+	age int64
+}
+
+// Padding between and after the fields: the padding bytes hold no value,
+// so the key is hashed field by field instead of as one block
+type paddedKey struct {
+	active bool
+	id     int64
+	age    int16
+}
+
+// A string field: hashing reads the string's bytes through its pointer
+type stringKey struct {
+	name string
+	age  int
+}
+
+// concatKey builds the key many programs use instead of a struct. Every
+// lookup copies the bytes into a new string, and without a separator
+// different pairs can give one key.
+func concatKey(name string, age int) string {
+	return name + strconv.Itoa(age)
+}
+
+// packedKey packs two small integers into one: age in the low 16 bits and
+// id in the 48 above. It is only a key while 0 <= id < 1<<48, since the
+// shift would drop the top bits of a larger id and make keys collide, so
+// such an id panics.
+func packedKey(id int64, age int16) uint64 {
+	if id < 0 || id >= 1<<48 {
+		panic(fmt.Sprintf("packedKey: id %d does not fit in 48 bits", id))
+	}
+	return uint64(id)<<16 | uint64(uint16(age))
+}
+
+// hitSink keeps the parallel benchmarks' lookups alive
+var hitSink atomic.Int64
+
+// lesson: mapkeys section=structs-as-map-keys goVersion=go1.19
+func mapKeys() {
+	// Alice and Bob in main() have the same fields, so each converts to the
+	// other; as map keys, equal field values mean the same key
+	type Alice struct {
+		name string
+		age  int
+	}
+	type Bob struct {
+		name string
+		age  int
+	}
+	visits := map[Alice]int{{name: "Alice", age: 25}: 1}
+	bob := Bob{name: "Alice", age: 25}
+	n, ok := visits[Alice(bob)]
+	fmt.Println("visits[Alice(bob)]:", n, ok)
+	visits[Alice{name: "Alice", age: 26}]++
+	fmt.Println("keys after a birthday:", len(visits))
+
+	fmt.Println("sizeof intKey:", unsafe.Sizeof(intKey{}), "paddedKey:", unsafe.Sizeof(paddedKey{}), "stringKey:", unsafe.Sizeof(stringKey{}))
+
+	// Concatenated keys collide where struct keys cannot
+	fmt.Println(`concatKey("Al1", 2) == concatKey("Al", 12):`, concatKey("Al1", 2) == concatKey("Al", 12))
+	byStruct := map[stringKey]int{{name: "Al1", age: 2}: 1, {name: "Al", age: 12}: 2}
+	fmt.Println("distinct struct keys:", len(byStruct))
+	fmt.Println("packedKey(7, 30):", packedKey(7, 30))
+
+	if !benchmarking() {
+		return
+	}
+	const keys = 1024 // keys per map; a power of two so i&(keys-1) cycles through them
+	names := make([]string, keys)
+	for i := range names {
+		names[i] = "person" + strconv.Itoa(i)
+	}
+	ints := make(map[intKey]int, keys)
+	padded := make(map[paddedKey]int, keys)
+	strs := make(map[stringKey]int, keys)
+	concat := make(map[string]int, keys)
+	packed := make(map[uint64]int, keys)
+	for i, name := range names {
+		ints[intKey{id: int64(i), age: 30}] = i
+		padded[paddedKey{active: true, id: int64(i), age: 30}] = i
+		strs[stringKey{name: name, age: 30}] = i
+		concat[concatKey(name, 30)] = i
+		packed[packedKey(int64(i), 30)] = i
+	}
+
+	bench := newBenchTable(false, true, "Lookup by", "key bytes")
+	bench.runSize("intKey", unsafe.Sizeof(intKey{}), func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			intSink = ints[intKey{id: int64(i & (keys - 1)), age: 30}]
+		}
+	})
+	bench.runSize("paddedKey", unsafe.Sizeof(paddedKey{}), func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			intSink = padded[paddedKey{active: true, id: int64(i & (keys - 1)), age: 30}]
+		}
+	})
+	bench.runSize("stringKey", unsafe.Sizeof(stringKey{}), func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			intSink = strs[stringKey{name: names[i&(keys-1)], age: 30}]
+		}
+	})
+	bench.runSize("concatKey", unsafe.Sizeof(""), func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			intSink = concat[concatKey(names[i&(keys-1)], 30)]
+		}
+	})
+	bench.runSize("packedKey", unsafe.Sizeof(uint64(0)), func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			intSink = packed[packedKey(int64(i&(keys-1)), 30)]
+		}
+	})
+	bench.flush()
+
+	// Maps shared between goroutines: a sync.Map against a plain map behind
+	// a Mutex or an RWMutex, for different mixes of reads and writes
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Reads\tsync.Map ns/op\tMutex ns/op\tRWMutex ns/op\t")
+	parallel := func(reads int, load func(k intKey) bool, store func(k intKey)) float64 {
+		res := testing.Benchmark(func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				hits := int64(0)
+				for i := 0; pb.Next(); i++ {
+					k := intKey{id: int64(i & (keys - 1)), age: 30}
+					if i%100 >= reads {
+						store(k)
+					} else if load(k) {
+						hits++
+					}
+				}
+				hitSink.Add(hits)
+			})
+		})
+		return float64(res.T.Nanoseconds()) / float64(res.N)
+	}
+	for _, reads := range []int{99, 90, 50, 10} {
+		var sm sync.Map
+		var mu sync.Mutex
+		var rw sync.RWMutex
+		m1 := make(map[intKey]int, keys)
+		m2 := make(map[intKey]int, keys)
+		for k, v := range ints {
+			sm.Store(k, v)
+			m1[k], m2[k] = v, v
+		}
+		syncMap := parallel(reads,
+			func(k intKey) bool {
+				_, ok := sm.Load(k)
+				return ok
+			},
+			func(k intKey) { sm.Store(k, 1) })
+		mutex := parallel(reads,
+			func(k intKey) bool {
+				mu.Lock()
+				defer mu.Unlock()
+				_, ok := m1[k]
+				return ok
+			},
+			func(k intKey) {
+				mu.Lock()
+				m1[k] = 1
+				mu.Unlock()
+			})
+		rwMutex := parallel(reads,
+			func(k intKey) bool {
+				rw.RLock()
+				defer rw.RUnlock()
+				_, ok := m2[k]
+				return ok
+			},
+			func(k intKey) {
+				rw.Lock()
+				m2[k] = 1
+				rw.Unlock()
+			})
+		fmt.Fprintf(tw, "%d%%\t%.2f\t%.2f\t%.2f\t\n", reads, syncMap, mutex, rwMutex)
+	}
+	tw.Flush()
+}
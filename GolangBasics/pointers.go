@@ -2,16 +2,17 @@
 
 package main 
 
+// lesson: pointer section=pointers--reference-semantics
 func pointer() {
 
-	increment := func(num int) {
+	increment := func(num int) { // INLINE "can inline pointer.func1"
 		num++
 		println("Inside : ", num, &num)
 	}
-	count := 42
+	count := 42 // stays on the stack: println(&count) does not move it to the heap
 
 	// Increment declares count as  pointer variable whose value is always an address and points to an integer value
-	incrementAddr := func(num *int) {
+	incrementAddr := func(num *int) { // INLINE "can inline pointer.func2" ESCAPE "num does not escape"
 		*num++
 		println("Inside Addr: ", num, &num)
 	}
@@ -21,10 +22,11 @@ func pointer() {
 	// Displays value of count and its memory address
 	println("Before : ", count, &count)
 	// Pass the value of count to the function
-	increment(count)
+	increment(count) // INLINE "inlining call to pointer.func1"
 	println("After : ", count, &count)
 
-	incrementAddr(&count) // Pass the address of count to the function
+	// Pass the address of count to the function
+	incrementAddr(&count) // INLINE "inlining call to pointer.func2"
 	println("After Addr: ", count, &count)
 
 	// Pass by Reference
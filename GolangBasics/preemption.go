@@ -0,0 +1,188 @@
+// Goroutine preemption: cooperative and asynchronous
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// spin is a tight loop: no function calls, so no stack-growth checks, the
+// points where a goroutine is preempted cooperatively. Since Go 1.14 the
+// runtime can still stop it by sending its thread a signal (asynchronous
+// preemption); GODEBUG=asyncpreemptoff=1 turns that off
+//
+//go:noinline
+func spin(n int) int {
+	x := 0
+	for i := 0; i < n; i++ {
+		x += i ^ (x >> 3)
+	}
+	return x
+}
+
+// preemptEnv makes a run of the lesson the measuring subprocess; its value
+// is the number of spin iterations
+const preemptEnv = "BASICS_PREEMPT_SPIN"
+
+// preemptRun is what one subprocess measured
+type preemptRun struct {
+	latencies []time.Duration // how late each 1ms sleep woke up
+	gc        time.Duration   // how long runtime.GC() took
+	gcWaited  bool            // whether runtime.GC() returned only after the loops ended
+}
+
+// preemptChild runs two tight loops on a GOMAXPROCS=3 runtime. The third
+// P is free, so a goroutine sleeping 1ms at a time should wake up on time,
+// and runtime.GC() only has to stop the world briefly. Stopping the world
+// needs every goroutine to stop, though, including the loops
+func preemptChild(n int) {
+	runtime.GOMAXPROCS(3)
+	var running sync.WaitGroup
+	var loopsDone atomic.Bool
+	var sink atomic.Int64
+	running.Add(2)
+	for range 2 {
+		go func() {
+			defer running.Done()
+			sink.Add(int64(spin(n)))
+		}()
+	}
+	go func() {
+		running.Wait()
+		loopsDone.Store(true)
+	}()
+
+	gcDone := make(chan string)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		start := time.Now()
+		runtime.GC()
+		gcDone <- fmt.Sprintf("gc %d %v", time.Since(start), loopsDone.Load())
+	}()
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	for !loopsDone.Load() {
+		start := time.Now()
+		time.Sleep(time.Millisecond)
+		fmt.Fprintln(out, "lat", time.Since(start)-time.Millisecond)
+	}
+	fmt.Fprintln(out, <-gcDone)
+}
+
+// runPreemptChild runs the lesson's binary as a subprocess measuring n
+// spin iterations, with GODEBUG extended by godebug if it is not empty
+func runPreemptChild(n int, godebug string) (preemptRun, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return preemptRun{}, err
+	}
+	cmd := exec.Command(exe)
+	cmd.Env = append(os.Environ(), preemptEnv+"="+strconv.Itoa(n))
+	if godebug != "" {
+		if old := os.Getenv("GODEBUG"); old != "" {
+			godebug = old + "," + godebug
+		}
+		cmd.Env = append(cmd.Env, "GODEBUG="+godebug)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return preemptRun{}, err
+	}
+	var r preemptRun
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		f := strings.Fields(line)
+		switch {
+		case len(f) == 2 && f[0] == "lat":
+			d, err := time.ParseDuration(f[1])
+			if err != nil {
+				return r, err
+			}
+			r.latencies = append(r.latencies, d)
+		case len(f) == 3 && f[0] == "gc":
+			ns, err := strconv.ParseInt(f[1], 10, 64)
+			if err != nil {
+				return r, err
+			}
+			r.gc, r.gcWaited = time.Duration(ns), f[2] == "true"
+		}
+	}
+	return r, nil
+}
+
+// lesson: preemption section=goroutine-preemption goVersion=go1.22
+func preemption() {
+	if s := os.Getenv(preemptEnv); s != "" {
+		n, _ := strconv.Atoi(s)
+		preemptChild(n)
+		return
+	}
+
+	// Calibrate spin to about 300ms on this machine
+	const probe = 10_000_000
+	start := time.Now()
+	spin(probe)
+	n := int(float64(probe) * float64(300*time.Millisecond) / float64(max(time.Since(start), time.Microsecond)))
+
+	modes := []struct{ name, godebug string }{
+		{"default", ""},
+		{"asyncpreemptoff=1", "asyncpreemptoff=1"},
+	}
+	fmt.Println("two tight loops of about 300ms each, GOMAXPROCS=3, runtime.GC() after 20ms:")
+	for _, m := range modes {
+		r, err := runPreemptChild(n, m.godebug)
+		if err != nil {
+			fmt.Println(m.name+":", err)
+			return
+		}
+		fmt.Printf("  %s: runtime.GC() waited for the loops to finish: %v\n", m.name, r.gcWaited)
+	}
+
+	if !benchmarking() {
+		return
+	}
+	// Latency histogram over several runs of each mode
+	const runs = 5
+	bounds := []time.Duration{100 * time.Microsecond, time.Millisecond, 10 * time.Millisecond, 100 * time.Millisecond}
+	labels := []string{"< 100µs", "100µs–1ms", "1ms–10ms", "10ms–100ms", "≥ 100ms"}
+	counts := make([][]int, len(modes))
+	worst := make([]time.Duration, len(modes))
+	gcs := make([][]time.Duration, len(modes))
+	for i, m := range modes {
+		counts[i] = make([]int, len(labels))
+		for range runs {
+			r, err := runPreemptChild(n, m.godebug)
+			if err != nil {
+				fmt.Println(m.name+":", err)
+				return
+			}
+			for _, d := range r.latencies {
+				b, _ := slices.BinarySearch(bounds, d+1)
+				counts[i][b]++
+				worst[i] = max(worst[i], d)
+			}
+			gcs[i] = append(gcs[i], r.gc)
+		}
+		slices.Sort(gcs[i])
+	}
+	fmt.Printf("\nwake-up latency of a goroutine sleeping 1ms at a time, %d runs:\n", runs)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Late by\tdefault\tasyncpreemptoff=1\t")
+	for b, label := range labels {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t\n", label, counts[0][b], counts[1][b])
+	}
+	fmt.Fprintf(tw, "max\t%v\t%v\t\n", worst[0].Round(time.Microsecond), worst[1].Round(time.Microsecond))
+	fmt.Fprintf(tw, "median runtime.GC()\t%v\t%v\t\n", gcs[0][runs/2].Round(time.Microsecond), gcs[1][runs/2].Round(time.Microsecond))
+	tw.Flush()
+}
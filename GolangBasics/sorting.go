@@ -0,0 +1,185 @@
+// Sorting and ordering slices of structs
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// person has the fields of Alice and Bob in main()
+type person struct {
+	name string
+	age  int
+}
+
+// byAgeThenName orders people by age, then by name: cmp.Or returns its
+// first non-zero argument, so the name only decides between equal ages
+func byAgeThenName(a, b person) int {
+	return cmp.Or(cmp.Compare(a.age, b.age), strings.Compare(a.name, b.name))
+}
+
+// byName implements sort.Interface, the interface the sort package used
+// before generics: the slice type supplies Len, Less and Swap
+type byName []person
+
+func (p byName) Len() int           { return len(p) }
+func (p byName) Less(i, j int) bool { return p[i].name < p[j].name }
+func (p byName) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// bigExample is example with a payload, large enough that moving it
+// costs more than following a pointer to it
+type bigExample struct {
+	example
+	id       int
+	readings [62]float64
+}
+
+// lesson: sorting section=sorting--ordering goVersion=go1.22
+func sorting() {
+	people := []person{
+		{name: "Carol", age: 35}, {name: "Alice", age: 30}, {name: "Dave", age: 25}, {name: "Bob", age: 30}, {name: "Eve", age: 25},
+	}
+
+	// slices.SortFunc with a three-way comparison: negative, zero or
+	// positive, as returned by cmp.Compare
+	sorted := slices.Clone(people)
+	slices.SortFunc(sorted, byAgeThenName)
+	fmt.Println("by age, then name:", sorted)
+	slices.SortFunc(sorted, func(a, b person) int { return cmp.Compare(b.age, a.age) })
+	fmt.Println("by age, descending:", ages(sorted))
+
+	// Stability: a stable sort keeps elements that compare equal in their
+	// original order. SortFunc is not stable: it only promises sorted ages,
+	// and equal ages may come out in any order, which can change between
+	// Go releases
+	var many []person
+	for i := range 24 {
+		many = append(many, person{name: fmt.Sprintf("p%02d", i), age: 20 + i%3})
+	}
+	byAge := func(a, b person) int { return cmp.Compare(a.age, b.age) }
+	stable := slices.Clone(many)
+	slices.SortStableFunc(stable, byAge)
+	unstable := slices.Clone(many)
+	slices.SortFunc(unstable, byAge)
+	fmt.Println("SortStableFunc kept equal ages in input order:", inInputOrder(stable))
+	fmt.Println("  age 20:", names(stable[:8]))
+	fmt.Println("SortFunc sorted by age:", slices.IsSortedFunc(unstable, byAge))
+	if benchmarking() {
+		// Not in the golden file, since it may change between releases
+		fmt.Println("SortFunc kept equal ages in input order:", inInputOrder(unstable))
+		fmt.Println("  age 20:", names(unstable[:8]))
+	}
+
+	// Binary search needs the slice sorted by the same order it searches
+	// with. It returns where the target is, or where it would be inserted
+	slices.SortFunc(sorted, byAgeThenName)
+	i, found := slices.BinarySearchFunc(sorted, person{name: "Bob", age: 30}, byAgeThenName)
+	fmt.Println("BinarySearchFunc Bob, 30:", i, found)
+	i, found = slices.BinarySearchFunc(sorted, person{name: "Ann", age: 30}, byAgeThenName)
+	fmt.Println("BinarySearchFunc Ann, 30:", i, found, "(insert here)")
+	sorted = slices.Insert(sorted, i, person{name: "Ann", age: 30})
+	fmt.Println("after Insert:", slices.IsSortedFunc(sorted, byAgeThenName), sorted)
+
+	// sort.Interface: sort.Sort is not stable either, sort.Stable is
+	named := slices.Clone(people)
+	sort.Sort(byName(named))
+	fmt.Println("sort.Sort(byName):", named, sort.IsSorted(byName(named)))
+	idx := sort.Search(len(named), func(i int) bool { return named[i].name >= "Dave" })
+	fmt.Println("sort.Search Dave:", idx)
+
+	// cmp.Compare orders NaN before every other float, so a slice holding
+	// NaN still sorts consistently; the < operator alone cannot
+	floats := []float64{2.5, math.NaN(), -1, 0}
+	slices.Sort(floats)
+	fmt.Println("slices.Sort with NaN:", floats)
+
+	if !benchmarking() {
+		return
+	}
+	// Sorting values moves whole structs; sorting pointers moves 8 bytes
+	// but each comparison follows a pointer to memory that may be anywhere
+	const n = 1000
+	r := rand.New(rand.NewSource(1))
+	perm := r.Perm(n)
+	smallVals := make([]example, n)
+	bigVals := make([]bigExample, n)
+	for i, p := range perm {
+		smallVals[i] = example{radius: int16(p)}
+		bigVals[i] = bigExample{id: p}
+	}
+	smallPtrs := make([]*example, n)
+	bigPtrs := make([]*bigExample, n)
+	for i := range perm {
+		smallPtrs[i] = &smallVals[i]
+		bigPtrs[i] = &bigVals[i]
+	}
+
+	bench := newBenchTable(true, false, fmt.Sprintf("Sort %d", n), "element bytes")
+	bench.runSize("[]example", unsafe.Sizeof(example{}), func(b *testing.B) {
+		s := make([]example, n)
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			copy(s, smallVals)
+			b.StartTimer()
+			slices.SortFunc(s, func(a, b example) int { return cmp.Compare(a.radius, b.radius) })
+		}
+	})
+	bench.runSize("[]*example", unsafe.Sizeof(&example{}), func(b *testing.B) {
+		s := make([]*example, n)
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			copy(s, smallPtrs)
+			b.StartTimer()
+			slices.SortFunc(s, func(a, b *example) int { return cmp.Compare(a.radius, b.radius) })
+		}
+	})
+	bench.runSize("[]bigExample", unsafe.Sizeof(bigExample{}), func(b *testing.B) {
+		s := make([]bigExample, n)
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			copy(s, bigVals)
+			b.StartTimer()
+			slices.SortFunc(s, func(a, b bigExample) int { return cmp.Compare(a.id, b.id) })
+		}
+	})
+	bench.runSize("[]*bigExample", unsafe.Sizeof(&bigExample{}), func(b *testing.B) {
+		s := make([]*bigExample, n)
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			copy(s, bigPtrs)
+			b.StartTimer()
+			slices.SortFunc(s, func(a, b *bigExample) int { return cmp.Compare(a.id, b.id) })
+		}
+	})
+	bench.flush()
+}
+
+func ages(people []person) []int {
+	var a []int
+	for _, p := range people {
+		a = append(a, p.age)
+	}
+	return a
+}
+
+func names(people []person) string {
+	var s []string
+	for _, p := range people {
+		s = append(s, p.name)
+	}
+	return strings.Join(s, " ")
+}
+
+// inInputOrder reports whether people with equal ages are still in the
+// order of their names, which is the order they were created in
+func inInputOrder(people []person) bool {
+	return slices.IsSortedFunc(people, byAgeThenName)
+}
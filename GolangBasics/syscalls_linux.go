@@ -0,0 +1,180 @@
+// System calls: crossing from the Go runtime into the Linux kernel
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// Clock IDs of clock_gettime(2); the syscall package does not export them
+const (
+	clockRealtime  = 0
+	clockMonotonic = 1
+)
+
+// clockGettime reads a clock with a raw system call. It cannot block, so
+// RawSyscall, which skips the scheduler's bookkeeping, is safe here.
+// unsafe.Pointer must be converted to uintptr inside the call expression:
+// only then does the compiler keep ts alive and in place until the call
+// returns
+func clockGettime(clock int) (syscall.Timespec, syscall.Errno) {
+	var ts syscall.Timespec
+	_, _, errno := syscall.RawSyscall(syscall.SYS_CLOCK_GETTIME, uintptr(clock), uintptr(unsafe.Pointer(&ts)), 0)
+	return ts, errno
+}
+
+// sleepIn blocks the calling thread in nanosleep(2) for d, through
+// syscall.Syscall or, if raw is set, syscall.RawSyscall. A signal
+// (the runtime sends them to preempt goroutines) interrupts the sleep with
+// EINTR; the kernel has then written the time left back into ts
+func sleepIn(d time.Duration, raw bool) {
+	ts := syscall.NsecToTimespec(int64(d))
+	for {
+		var errno syscall.Errno
+		if raw {
+			_, _, errno = syscall.RawSyscall(syscall.SYS_NANOSLEEP, uintptr(unsafe.Pointer(&ts)), uintptr(unsafe.Pointer(&ts)), 0)
+		} else {
+			_, _, errno = syscall.Syscall(syscall.SYS_NANOSLEEP, uintptr(unsafe.Pointer(&ts)), uintptr(unsafe.Pointer(&ts)), 0)
+		}
+		if errno != syscall.EINTR {
+			return
+		}
+	}
+}
+
+// lesson: syscalls section=system-calls-crossing-into-the-kernel goVersion=go1.22 goos=linux
+func rawSyscalls() {
+	// write(2): the kernel copies len(msg) bytes from our memory to fd 1.
+	// The results are the kernel's return value and an errno, not a Go error
+	msg := []byte("hello from write(2)\n")
+	n, _, errno := syscall.Syscall(syscall.SYS_WRITE, 1, uintptr(unsafe.Pointer(&msg[0])), uintptr(len(msg)))
+	fmt.Println("write: n =", n, "errno =", int(errno))
+	// os.File.Write makes the same system call, retries on EINTR and turns
+	// errno into an *os.PathError
+	os.Stdout.Write([]byte("hello from os.Stdout.Write\n"))
+
+	// A failing call: the kernel returns -1 and sets errno
+	r1, _, errno := syscall.Syscall(syscall.SYS_WRITE, ^uintptr(0), uintptr(unsafe.Pointer(&msg[0])), uintptr(len(msg)))
+	fmt.Println("write(-1): r1 =", int(r1), "errno =", int(errno), errno)
+	// os reports the same errno as an *os.PathError; here the kernel refuses
+	// to write to a file opened read-only
+	ro, err := os.Open(os.DevNull)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	_, err = ro.Write(msg)
+	ro.Close()
+	fmt.Println("os equivalent:", err, errors.Is(err, syscall.EBADF))
+
+	// getpid(2) never blocks, so RawSyscall is enough
+	pid, _, _ := syscall.RawSyscall(syscall.SYS_GETPID, 0, 0, 0)
+	fmt.Println("getpid == os.Getpid():", int(pid) == os.Getpid())
+
+	// clock_gettime(2): the monotonic clock never goes backwards; the
+	// realtime clock is what time.Now reads, without entering the kernel
+	t1, _ := clockGettime(clockMonotonic)
+	t2, _ := clockGettime(clockMonotonic)
+	fmt.Println("monotonic clock never goes backwards:", t2.Nano() >= t1.Nano())
+	rt, _ := clockGettime(clockRealtime)
+	diff := time.Now().UnixNano() - rt.Nano()
+	fmt.Println("CLOCK_REALTIME within a second of time.Now():", diff >= 0 && diff < int64(time.Second))
+
+	if !benchmarking() {
+		return
+	}
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer devNull.Close()
+	fd := devNull.Fd()
+
+	bench := newBenchTable(false, false, "Call")
+	bench.run("getpid RawSyscall", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			syscall.RawSyscall(syscall.SYS_GETPID, 0, 0, 0)
+		}
+	})
+	bench.run("getpid Syscall", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			syscall.Syscall(syscall.SYS_GETPID, 0, 0, 0)
+		}
+	})
+	bench.run("os.Getpid", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			os.Getpid()
+		}
+	})
+	bench.run("clock_gettime RawSyscall", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			clockGettime(clockRealtime)
+		}
+	})
+	bench.run("time.Now (vDSO)", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			time.Now()
+		}
+	})
+	bench.run("write 1 byte Syscall", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			syscall.Syscall(syscall.SYS_WRITE, fd, uintptr(unsafe.Pointer(&msg[0])), 1)
+		}
+	})
+	bench.run("write 1 byte os.File", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			devNull.Write(msg[:1])
+		}
+	})
+	bench.flush()
+
+	// P handoff: with one P, a goroutine blocked in a system call made with
+	// Syscall gives up its P, and the runtime hands it to another thread
+	// (idle or new) to run other goroutines meanwhile. RawSyscall does not tell the
+	// scheduler, so the P stays with the blocked thread and the other
+	// goroutines wait
+	fmt.Println()
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	for _, raw := range []bool{false, true} {
+		var ticks atomic.Int64
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					ticks.Add(1)
+					runtime.Gosched()
+				}
+			}
+		}()
+		runtime.Gosched() // let the counter start
+		before := ticks.Load()
+		start := time.Now()
+		for range 5 {
+			sleepIn(10*time.Millisecond, raw)
+		}
+		elapsed := time.Since(start)
+		during := ticks.Load() - before
+		close(stop)
+		<-done
+		name := "Syscall"
+		if raw {
+			name = "RawSyscall"
+		}
+		fmt.Printf("5 × nanosleep(10ms) via %-10s: %v, other goroutine ran %d loops meanwhile\n",
+			name, elapsed.Round(time.Millisecond), during)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+// System calls: the lesson is in syscalls_linux.go
+
+package main
+
+// rawSyscalls stands in for the Linux-only syscalls lesson so that the
+// lessons table compiles everywhere; the lesson is declared goos=linux,
+// so it is never run here.
+func rawSyscalls() {}